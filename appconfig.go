@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+)
+
+// ExpoConfig is the Expo app config, resolved from app.json or the dynamic app.config.js / app.config.ts.
+type ExpoConfig struct {
+	Name       string `json:"name"`
+	Slug       string `json:"slug"`
+	Version    string `json:"version"`
+	SDKVersion string `json:"sdkVersion"`
+	IOS        struct {
+		BundleIdentifier string `json:"bundleIdentifier"`
+		BuildNumber      string `json:"buildNumber"`
+	} `json:"ios"`
+	Android struct {
+		Package     string `json:"package"`
+		VersionCode int    `json:"versionCode"`
+	} `json:"android"`
+	Updates struct {
+		URL     string `json:"url"`
+		Enabled *bool  `json:"enabled"`
+	} `json:"updates"`
+	// RawPlugins are plugin names or [name, options] pairs, see Plugins.
+	RawPlugins []json.RawMessage `json:"plugins"`
+}
+
+// Plugins returns the names of the config plugins.
+func (c ExpoConfig) Plugins() []string {
+	var plugins []string
+	for _, raw := range c.RawPlugins {
+		var name string
+		if err := json.Unmarshal(raw, &name); err == nil {
+			plugins = append(plugins, name)
+			continue
+		}
+
+		var withOptions []json.RawMessage
+		if err := json.Unmarshal(raw, &withOptions); err == nil && len(withOptions) > 0 {
+			if err := json.Unmarshal(withOptions[0], &name); err == nil {
+				plugins = append(plugins, name)
+			}
+		}
+	}
+	return plugins
+}
+
+// UpdatesEnabled reports whether expo-updates is not disabled in the config.
+func (c ExpoConfig) UpdatesEnabled() bool {
+	return c.Updates.Enabled == nil || *c.Updates.Enabled
+}
+
+// SDKMajorVersion returns the major version of the config's Expo SDK.
+func (c ExpoConfig) SDKMajorVersion() (int, error) {
+	if c.SDKVersion == "" {
+		return 0, fmt.Errorf("no sdkVersion in the Expo config")
+	}
+	return parseSDKMajorVersion(c.SDKVersion)
+}
+
+// parseExpoConfig parses the JSON printed by `expo config --json`, which is either the app config
+// or the full project config with the app config under exp. Log lines around the JSON are ignored.
+func parseExpoConfig(out []byte) (ExpoConfig, error) {
+	start := bytes.IndexByte(out, '{')
+	end := bytes.LastIndexByte(out, '}')
+	if start == -1 || end < start {
+		return ExpoConfig{}, fmt.Errorf("no JSON object in the output: %s", out)
+	}
+	out = out[start : end+1]
+
+	var full struct {
+		Exp *json.RawMessage `json:"exp"`
+	}
+	if err := json.Unmarshal(out, &full); err != nil {
+		return ExpoConfig{}, err
+	}
+	if full.Exp != nil {
+		out = *full.Exp
+	}
+
+	var config ExpoConfig
+	if err := json.Unmarshal(out, &config); err != nil {
+		return ExpoConfig{}, err
+	}
+	return config, nil
+}
+
+// loadStaticExpoConfig reads the Expo config from app.json without running the Expo CLI, so it is available
+// before the CLI is installed. The config is empty if there is no app.json (only a dynamic config),
+// the SDK version falls back to the expo dependency in package.json, as the Expo CLI resolves it.
+func loadStaticExpoConfig(workdir string) (ExpoConfig, error) {
+	var config ExpoConfig
+
+	appJSONPth := filepath.Join(workdir, "app.json")
+	if exist, err := pathutil.IsPathExists(appJSONPth); err != nil {
+		return ExpoConfig{}, err
+	} else if exist {
+		b, err := fileutil.ReadBytesFromFile(appJSONPth)
+		if err != nil {
+			return ExpoConfig{}, fmt.Errorf("Failed to read app.json file: %s", err)
+		}
+
+		var appJSON struct {
+			Expo *json.RawMessage `json:"expo"`
+		}
+		if err := json.Unmarshal(b, &appJSON); err != nil {
+			return ExpoConfig{}, fmt.Errorf("Failed to parse app.json file: %s", err)
+		}
+		// The expo key is optional in app.json.
+		if appJSON.Expo != nil {
+			b = *appJSON.Expo
+		}
+		if err := json.Unmarshal(b, &config); err != nil {
+			return ExpoConfig{}, fmt.Errorf("Failed to parse app.json file: %s", err)
+		}
+	}
+
+	if config.SDKVersion == "" {
+		packages, err := parsePackageJSON(filepath.Join(workdir, "package.json"))
+		if err != nil {
+			return ExpoConfig{}, err
+		}
+		if deps, err := packages.Object("dependencies"); err == nil {
+			config.SDKVersion, _ = deps.String("expo")
+		}
+	}
+
+	return config, nil
+}
+
+// resolveExpoConfig evaluates the Expo config with `expo config --json`, which supports dynamic configs.
+// Falls back to the static config if the Expo CLI fails.
+func (e Expo) resolveExpoConfig(ctx context.Context) (ExpoConfig, error) {
+	cmd := e.expoCommand("config", "--json")
+	// Classic expo-cli prompts for missing values without it.
+	if e.Mode == EjectModeEject {
+		cmd.Args = append(cmd.Args, "--non-interactive")
+	}
+
+	if e.Plan != nil {
+		// Evaluating the config runs the project's app.config.js or app.config.ts, the dry run reads app.json only.
+		e.Plan.AddCommand(cmd)
+		log.Printf("Dry run: reading app.json instead of running %s", cmd)
+		return loadStaticExpoConfig(e.Workdir)
+	}
+
+	log.Donef("$ %s", cmd)
+	// Evaluating app.config.js or app.config.ts may hang, it is limited by the eject phase's timeout.
+	var out string
+	err := e.inPhase(ctx, PhaseEject, func(ctx context.Context) error {
+		var err error
+		out, err = e.Runner.Output(ctx, cmd)
+		return err
+	})
+	if err == nil {
+		config, parseErr := parseExpoConfig([]byte(out))
+		if parseErr == nil {
+			return config, nil
+		}
+		err = parseErr
+	}
+
+	log.Warnf("Failed to resolve the Expo config, reading app.json instead: %s", err)
+	return loadStaticExpoConfig(e.Workdir)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestExpoResolveExpoConfig(t *testing.T) {
+	dir := createProject(t, projectFiles)
+
+	runner := newFakeCommandRunner()
+	runner.outputs["npx expo config --json"] = `Some warning printed by a config plugin
+{
+  "exp": {
+    "name": "Dynamic App",
+    "slug": "dynamic-app",
+    "sdkVersion": "49.0.0",
+    "ios": {"bundleIdentifier": "io.bitrise.dynamic"},
+    "android": {"package": "io.bitrise.dynamic", "versionCode": 3},
+    "updates": {"url": "https://u.expo.dev/abc", "enabled": false},
+    "plugins": ["expo-router", ["expo-build-properties", {"ios": {}}]]
+  },
+  "pkg": {"name": "dynamic-app"}
+}`
+
+	e := newTestExpo(dir, runner)
+	e.Mode = EjectModePrebuild
+	e.CLI = ExpoCLI{Command: []string{"npx", "expo"}}
+
+	config, err := e.resolveExpoConfig(context.Background())
+	if err != nil {
+		t.Fatalf("resolveExpoConfig(context.Background()) error = %v", err)
+	}
+
+	if config.Name != "Dynamic App" || config.Slug != "dynamic-app" {
+		t.Errorf("name, slug = %q, %q", config.Name, config.Slug)
+	}
+	if sdk, err := config.SDKMajorVersion(); err != nil || sdk != 49 {
+		t.Errorf("SDKMajorVersion() = %d, %v, want 49", sdk, err)
+	}
+	if config.IOS.BundleIdentifier != "io.bitrise.dynamic" || config.Android.Package != "io.bitrise.dynamic" || config.Android.VersionCode != 3 {
+		t.Errorf("native identifiers = %+v, %+v", config.IOS, config.Android)
+	}
+	if config.Updates.URL != "https://u.expo.dev/abc" || config.UpdatesEnabled() {
+		t.Errorf("updates = %q, enabled: %v", config.Updates.URL, config.UpdatesEnabled())
+	}
+	if got, want := config.Plugins(), []string{"expo-router", "expo-build-properties"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Plugins() = %v, want %v", got, want)
+	}
+}
+
+func TestExpoResolveExpoConfig_FallsBackToAppJSON(t *testing.T) {
+	dir := createProject(t, projectFiles)
+
+	runner := newFakeCommandRunner()
+	runner.errors["expo config"] = errors.New("exit status 1")
+
+	config, err := newTestExpo(dir, runner).resolveExpoConfig(context.Background())
+	if err != nil {
+		t.Fatalf("resolveExpoConfig(context.Background()) error = %v", err)
+	}
+	if config.Name != "app" {
+		t.Errorf("name = %q, want app", config.Name)
+	}
+	// app.json has no sdkVersion, it is read from the expo dependency.
+	if sdk, err := config.SDKMajorVersion(); err != nil || sdk != 39 {
+		t.Errorf("SDKMajorVersion() = %d, %v, want 39", sdk, err)
+	}
+}
+
+func TestExpoResolveExpoConfig_DryRun(t *testing.T) {
+	dir := createProject(t, projectFiles)
+
+	runner := newFakeCommandRunner()
+	e := newTestExpo(dir, runner)
+	e.Plan = NewPlan()
+
+	config, err := e.resolveExpoConfig(context.Background())
+	if err != nil {
+		t.Fatalf("resolveExpoConfig(context.Background()) error = %v", err)
+	}
+	if config.Name != "app" {
+		t.Errorf("name = %q, want app", config.Name)
+	}
+	if len(runner.commands) != 0 {
+		t.Errorf("commands = %v, want none", runner.lines())
+	}
+	if want := []string{`$ expo "config" "--json" "--non-interactive" (in ` + dir + ")"}; !reflect.DeepEqual(e.Plan.steps, want) {
+		t.Errorf("plan = %v, want %v", e.Plan.steps, want)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/fileutil"
+)
+
+const (
+	appVersionKey         = "EXPO_APP_VERSION"
+	iosBuildNumberKey     = "EXPO_IOS_BUILD_NUMBER"
+	androidVersionCodeKey = "EXPO_ANDROID_VERSION_CODE"
+
+	// incrementBuildNumber increments the build numbers already set in app.json.
+	incrementBuildNumber = "increment"
+)
+
+// appVersions are the version fields set in app.json, empty if not changed.
+type appVersions struct {
+	Version            string
+	IOSBuildNumber     string
+	AndroidVersionCode string
+}
+
+// validateBuildNumber checks that the build_number input is empty, a number or increment.
+func validateBuildNumber(buildNumber string) error {
+	if buildNumber == "" || buildNumber == incrementBuildNumber {
+		return nil
+	}
+	if n, err := strconv.Atoi(buildNumber); err != nil || n < 0 {
+		return fmt.Errorf("build number (%s) should be a non-negative integer or %s", buildNumber, incrementBuildNumber)
+	}
+	return nil
+}
+
+// nextBuildNumber returns the build number to set: the input, or the current value incremented by one, plus the offset.
+func nextBuildNumber(buildNumber string, offset int, current func() (int, error)) (int, error) {
+	base := 0
+	if buildNumber == incrementBuildNumber {
+		n, err := current()
+		if err != nil {
+			return 0, err
+		}
+		base = n + 1
+	} else {
+		n, err := strconv.Atoi(buildNumber)
+		if err != nil {
+			return 0, err
+		}
+		base = n
+	}
+	return base + offset, nil
+}
+
+// appConfigKeys returns the key path of an app config field in the app.json document:
+// under the expo key, or at the root if app.json has no expo key.
+func appConfigKeys(appJSON *jsonDocument, keys ...string) []string {
+	if _, err := appJSON.Object("expo"); err != nil {
+		return keys
+	}
+	return append([]string{"expo"}, keys...)
+}
+
+// setAppVersions sets the version, ios.buildNumber and android.versionCode fields in the app.json document.
+func setAppVersions(appJSON *jsonDocument, version, buildNumber string, offset int, platform Platform) (appVersions, error) {
+	var versions appVersions
+
+	if version != "" {
+		keys := appConfigKeys(appJSON, "version")
+		if err := appJSON.Set(version, keys...); err != nil {
+			return appVersions{}, fmt.Errorf("Failed to set %s: %s", strings.Join(keys, "."), err)
+		}
+		versions.Version = version
+	}
+
+	if buildNumber == "" {
+		return versions, nil
+	}
+
+	if platform.IOS() {
+		keys := appConfigKeys(appJSON, "ios", "buildNumber")
+		n, err := nextBuildNumber(buildNumber, offset, func() (int, error) {
+			ios, err := appJSON.Object(keys[:len(keys)-1]...)
+			if err != nil {
+				return 0, nil
+			}
+			current, err := ios.Value("buildNumber")
+			if err != nil {
+				return 0, nil
+			}
+			if s, ok := current.(string); ok {
+				return strconv.Atoi(s)
+			}
+			return 0, fmt.Errorf("buildNumber is not a string: %v", current)
+		})
+		if err != nil {
+			return appVersions{}, fmt.Errorf("Failed to determine %s: %s", strings.Join(keys, "."), err)
+		}
+
+		versions.IOSBuildNumber = strconv.Itoa(n)
+		if err := appJSON.Set(versions.IOSBuildNumber, keys...); err != nil {
+			return appVersions{}, fmt.Errorf("Failed to set %s: %s", strings.Join(keys, "."), err)
+		}
+	}
+
+	if platform.Android() {
+		keys := appConfigKeys(appJSON, "android", "versionCode")
+		n, err := nextBuildNumber(buildNumber, offset, func() (int, error) {
+			android, err := appJSON.Object(keys[:len(keys)-1]...)
+			if err != nil {
+				return 0, nil
+			}
+			current, err := android.Value("versionCode")
+			if err != nil {
+				return 0, nil
+			}
+			if f, ok := current.(float64); ok {
+				return int(f), nil
+			}
+			return 0, fmt.Errorf("versionCode is not a number: %v", current)
+		})
+		if err != nil {
+			return appVersions{}, fmt.Errorf("Failed to determine %s: %s", strings.Join(keys, "."), err)
+		}
+
+		versions.AndroidVersionCode = strconv.Itoa(n)
+		if err := appJSON.Set(n, keys...); err != nil {
+			return appVersions{}, fmt.Errorf("Failed to set %s: %s", strings.Join(keys, "."), err)
+		}
+	}
+
+	return versions, nil
+}
+
+// injectAppVersions sets the version inputs in app.json and exports the final values.
+// A dry run adds the app.json diff to the plan, without writing the file or exporting the values.
+func injectAppVersions(e Expo, cfg Config) error {
+	appJSONPth := filepath.Join(cfg.Workdir, "app.json")
+	b, err := fileutil.ReadBytesFromFile(appJSONPth)
+	if err != nil {
+		return fmt.Errorf("Failed to read app.json file, setting the version requires a static app.json: %s", err)
+	}
+	appJSON, err := newJSONDocument(b)
+	if err != nil {
+		return fmt.Errorf("Failed to parse app.json file: %s", err)
+	}
+
+	versions, err := setAppVersions(appJSON, cfg.AppVersion, cfg.BuildNumber, cfg.BuildNumberOffset, cfg.Platforms)
+	if err != nil {
+		return err
+	}
+
+	if e.Plan != nil {
+		e.Plan.AddFileChange("app.json", b, appJSON.Bytes())
+		return nil
+	}
+	if err := fileutil.WriteBytesToFile(appJSONPth, appJSON.Bytes()); err != nil {
+		return fmt.Errorf("Failed to write modified app.json file: %s", err)
+	}
+
+	return exportOutputs(
+		output{appVersionKey, versions.Version},
+		output{iosBuildNumberKey, versions.IOSBuildNumber},
+		output{androidVersionCodeKey, versions.AndroidVersionCode},
+	)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSetAppVersions(t *testing.T) {
+	const appJSON = `{
+  "expo": {
+    "name": "app",
+    "ios": {
+      "buildNumber": "41"
+    },
+    "android": {
+      "versionCode": 12
+    }
+  }
+}
+`
+
+	tests := []struct {
+		name string
+		// appJSON overrides the default app.json.
+		appJSON     string
+		version     string
+		buildNumber string
+		offset      int
+		platform    Platform
+		want        appVersions
+		wantJSON    string
+		wantErr     bool
+	}{
+		{
+			name:        "set",
+			version:     "1.2.0",
+			buildNumber: "100",
+			offset:      5,
+			platform:    PlatformAll,
+			want:        appVersions{Version: "1.2.0", IOSBuildNumber: "105", AndroidVersionCode: "105"},
+			wantJSON: `{
+  "expo": {
+    "name": "app",
+    "ios": {
+      "buildNumber": "105"
+    },
+    "android": {
+      "versionCode": 105
+    },
+    "version": "1.2.0"
+  }
+}
+`,
+		},
+		{
+			name:        "increment android only",
+			buildNumber: incrementBuildNumber,
+			platform:    PlatformAndroid,
+			want:        appVersions{AndroidVersionCode: "13"},
+			wantJSON: `{
+  "expo": {
+    "name": "app",
+    "ios": {
+      "buildNumber": "41"
+    },
+    "android": {
+      "versionCode": 13
+    }
+  }
+}
+`,
+		},
+		{
+			name: "root level config without expo key",
+			appJSON: `{
+  "name": "app",
+  "ios": {
+    "buildNumber": "7"
+  }
+}
+`,
+			version:     "2.0.0",
+			buildNumber: incrementBuildNumber,
+			platform:    PlatformAll,
+			want:        appVersions{Version: "2.0.0", IOSBuildNumber: "8", AndroidVersionCode: "1"},
+			wantJSON: `{
+  "name": "app",
+  "ios": {
+    "buildNumber": "8"
+  },
+  "version": "2.0.0",
+  "android": {
+    "versionCode": 1
+  }
+}
+`,
+		},
+		{
+			name: "increment non-string ios build number",
+			appJSON: `{
+  "expo": {
+    "ios": {
+      "buildNumber": 41
+    }
+  }
+}
+`,
+			buildNumber: incrementBuildNumber,
+			platform:    PlatformIOS,
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := appJSON
+			if tt.appJSON != "" {
+				content = tt.appJSON
+			}
+			doc, err := newJSONDocument([]byte(content))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := setAppVersions(doc, tt.version, tt.buildNumber, tt.offset, tt.platform)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got versions %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("versions = %+v, want %+v", got, tt.want)
+			}
+			if string(doc.Bytes()) != tt.wantJSON {
+				t.Errorf("app.json =\n%s\nwant\n%s", doc.Bytes(), tt.wantJSON)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// expoHomeDirEnvKey overrides the Expo CLI's home directory (~/.expo), which stores the login session.
+const expoHomeDirEnvKey = "__UNSAFE_EXPO_HOME_DIRECTORY"
+
+// cleanupStack collects the teardown of the resources acquired by the Step (like the Expo session),
+// so they are released on every exit path: on return, on panic and on SIGINT or SIGTERM.
+type cleanupStack struct {
+	mu    sync.Mutex
+	items []*cleanupItem
+}
+
+type cleanupItem struct {
+	name string
+	fn   func()
+	once sync.Once
+}
+
+// push registers a cleanup function, and returns a function which runs it early.
+// Every cleanup function runs at most once.
+func (s *cleanupStack) push(name string, fn func()) func() {
+	item := &cleanupItem{name: name, fn: fn}
+
+	s.mu.Lock()
+	s.items = append(s.items, item)
+	s.mu.Unlock()
+
+	return func() {
+		item.once.Do(item.fn)
+	}
+}
+
+// run runs the registered cleanup functions in reverse order.
+func (s *cleanupStack) run() {
+	s.mu.Lock()
+	items := s.items
+	s.items = nil
+	s.mu.Unlock()
+
+	for i := len(items) - 1; i >= 0; i-- {
+		item := items[i]
+		item.once.Do(func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Warnf("Failed to %s: %v", item.name, r)
+				}
+			}()
+			item.fn()
+		})
+	}
+}
+
+// handleSignals cancels the Step's context if the Step receives SIGINT or SIGTERM (like on a build timeout).
+// The cancel kills the running command, so the Step returns and its deferred cleanup runs in the main goroutine,
+// further signals are ignored until the returned function stops the signal handling.
+func handleSignals(cancel context.CancelFunc) func() {
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-signals:
+			fmt.Println()
+			log.Warnf("Received %s, cleaning up", sig)
+			cancel()
+		case <-done:
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// isolateExpoHome creates a temporary Expo home directory, which is removed by the cleanup.
+func isolateExpoHome(cleanup *cleanupStack) (string, error) {
+	dir, err := ioutil.TempDir("", "expo-home")
+	if err != nil {
+		return "", fmt.Errorf("Failed to create temporary Expo home directory: %s", err)
+	}
+
+	cleanup.push("remove the temporary Expo home directory", func() {
+		if err := os.RemoveAll(dir); err != nil {
+			log.Warnf("Failed to remove the temporary Expo home directory: %s", err)
+		}
+	})
+	return dir, nil
+}
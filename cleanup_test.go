@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-io/go-utils/pathutil"
+)
+
+func TestCleanupStack(t *testing.T) {
+	var calls []string
+	cleanup := &cleanupStack{}
+	cleanup.push("first", func() { calls = append(calls, "first") })
+	runSecond := cleanup.push("second", func() { calls = append(calls, "second") })
+	cleanup.push("panicking", func() { panic("boom") })
+	cleanup.push("third", func() { calls = append(calls, "third") })
+
+	runSecond()
+	cleanup.run()
+	cleanup.run()
+	runSecond()
+
+	if want := []string{"second", "third", "first"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestAuthenticatedDetach_LogoutOnPanic(t *testing.T) {
+	workdir := createProject(t, projectFiles)
+	runner := newFakeCommandRunner()
+	runner.hooks["expo eject"] = func(cmd Command) {
+		panic("unexpected eject failure")
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatal("authenticatedDetach() did not panic")
+			}
+		}()
+		cfg := Config{Workdir: workdir, UserName: "user", Password: "pass", RunPublish: "no"}
+		_ = authenticatedDetach(context.Background(), newTestExpo(workdir, runner), cfg, &cleanupStack{})
+	}()
+
+	want := []string{"expo login --non-interactive -u user -p pass", "expo eject --non-interactive", "expo logout --non-interactive"}
+	if got := runner.lines(); !reflect.DeepEqual(got, want) {
+		t.Errorf("commands = %v, want %v", got, want)
+	}
+}
+
+// signalTestDirEnvKey makes TestHelperSignalledRun run, in the subprocess started by TestHandleSignals.
+const signalTestDirEnvKey = "EXPO_DETACH_SIGNAL_TEST_DIR"
+
+// TestHelperSignalledRun runs a command with the same cleanup setup as run(), until it is killed by a signal.
+// Each cleanup function writes a marker file into the test directory.
+func TestHelperSignalledRun(t *testing.T) {
+	dir := os.Getenv(signalTestDirEnvKey)
+	if dir == "" {
+		t.Skip("only run by TestHandleSignals")
+	}
+	marker := func(name string) {
+		if err := fileutil.WriteStringToFile(filepath.Join(dir, name), ""); err != nil {
+			t.Log(err)
+		}
+	}
+
+	err := func() error {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		cleanup := &cleanupStack{}
+		stopSignalHandling := handleSignals(cancel)
+		defer stopSignalHandling()
+		defer cleanup.run()
+
+		cleanup.push("remove the temporary home", func() { marker("home") })
+		// A logout takes a while, the Step must not exit before the earlier cleanups run.
+		cleanup.push("log out", func() {
+			time.Sleep(500 * time.Millisecond)
+			marker("logout")
+		})
+
+		marker("started")
+		return newDefaultCommandRunner().Run(ctx, NewCommand("sleep", "30"))
+	}()
+	if err == nil {
+		os.Exit(2)
+	}
+	os.Exit(1)
+}
+
+func TestHandleSignals(t *testing.T) {
+	dir := createProject(t, nil)
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperSignalledRun$")
+	cmd.Env = append(os.Environ(), signalTestDirEnvKey+"="+dir)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	started := false
+	for i := 0; i < 100 && !started; i++ {
+		time.Sleep(50 * time.Millisecond)
+		started, _ = pathutil.IsPathExists(filepath.Join(dir, "started"))
+	}
+	if !started {
+		_ = cmd.Process.Kill()
+		t.Fatal("the helper process did not start")
+	}
+
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Wait(); err == nil {
+		t.Error("the helper process exited successfully, want the killed command's error")
+	}
+
+	for _, name := range []string{"logout", "home"} {
+		if exist, err := pathutil.IsPathExists(filepath.Join(dir, name)); err != nil || !exist {
+			t.Errorf("cleanup %s did not run: %v", name, err)
+		}
+	}
+}
+
+func TestIsolateExpoHome(t *testing.T) {
+	cleanup := &cleanupStack{}
+	dir, err := isolateExpoHome(cleanup)
+	if err != nil {
+		t.Fatalf("isolateExpoHome() error = %v", err)
+	}
+
+	e := newTestExpo("/project", newFakeCommandRunner())
+	e.HomeDir = dir
+	if got, want := e.expoCommand("whoami").Envs, []string{expoHomeDirEnvKey + "=" + dir}; !reflect.DeepEqual(got, want) {
+		t.Errorf("envs = %v, want %v", got, want)
+	}
+
+	cleanup.run()
+	if exist, err := pathutil.IsPathExists(dir); err != nil || exist {
+		t.Errorf("Expo home directory exists after the cleanup: %v, %v", exist, err)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+)
+
+// unknownCLIVersion is the version of a CLI which failed to print its version.
+const unknownCLIVersion = "unknown"
+
+// ExpoCLI is the resolved Expo CLI binary.
+type ExpoCLI struct {
+	// Command is the binary followed by the arguments preceding the Expo CLI command.
+	Command []string
+	Version string
+	// Source describes where the binary was found.
+	Source string
+}
+
+// String ...
+func (c ExpoCLI) String() string {
+	return fmt.Sprintf("%s (version: %s, %s)", strings.Join(c.Command, " "), c.Version, c.Source)
+}
+
+// resolveExpoCLI looks for the project-local Expo CLI first, then falls back to npx in prebuild mode,
+// or to the global expo-cli in eject mode, which is installed only if missing or not the pinned version.
+func (e Expo) resolveExpoCLI(ctx context.Context) (ExpoCLI, error) {
+	if cli, ok := e.localExpoCLI(ctx); ok {
+		return cli, nil
+	}
+
+	if e.Mode == EjectModePrebuild {
+		cli := ExpoCLI{Command: []string{"npx", "expo"}, Source: "npx"}
+		if e.Plan != nil {
+			// npx may download the Expo CLI, the query is only planned.
+			e.Plan.AddCommand(e.versionCommand(cli))
+			cli.Version = unknownCLIVersion
+			return cli, nil
+		}
+		cli.Version = e.expoCLIVersion(ctx, cli)
+		return cli, nil
+	}
+
+	if pth, err := exec.LookPath("expo"); err == nil {
+		cli := ExpoCLI{Command: []string{pth}, Source: "global expo-cli"}
+		cli.Version = e.expoCLIVersion(ctx, cli)
+		if e.Version == "latest" || cli.Version == e.Version {
+			return cli, nil
+		}
+		log.Printf("Global expo-cli version (%s) does not match the selected version (%s)", cli.Version, e.Version)
+	}
+
+	if err := e.installExpoCLI(ctx); err != nil {
+		return ExpoCLI{}, err
+	}
+	if e.Plan != nil {
+		return ExpoCLI{Command: []string{"expo"}, Version: e.Version, Source: "global expo-cli to be installed"}, nil
+	}
+	pth, err := exec.LookPath("expo")
+	if err != nil {
+		return ExpoCLI{}, fmt.Errorf("expo not found in PATH after the install: %s", err)
+	}
+	cli := ExpoCLI{Command: []string{pth}, Source: "installed global expo-cli"}
+	cli.Version = e.expoCLIVersion(ctx, cli)
+	return cli, nil
+}
+
+// localExpoCLI returns the Expo CLI installed in the project's node_modules.
+func (e Expo) localExpoCLI(ctx context.Context) (ExpoCLI, bool) {
+	candidates := []ExpoCLI{
+		{Command: []string{filepath.Join(e.Workdir, "node_modules", ".bin", "expo")}, Source: "project-local node_modules/.bin/expo"},
+		{Command: []string{"node", filepath.Join(e.Workdir, "node_modules", "@expo", "cli", "build", "bin", "cli")}, Source: "project-local @expo/cli"},
+	}
+
+	for _, cli := range candidates {
+		if exist, err := pathutil.IsPathExists(cli.Command[len(cli.Command)-1]); err != nil {
+			log.Warnf("Failed to check if %s exists: %s", cli.Command[len(cli.Command)-1], err)
+			continue
+		} else if !exist {
+			continue
+		}
+
+		cli.Version = e.expoCLIVersion(ctx, cli)
+		// @expo/cli versions are 0.x, it does not support the classic eject.
+		// A CLI of unknown version may be @expo/cli too.
+		if e.Mode == EjectModeEject && (strings.HasPrefix(cli.Version, "0.") || cli.Version == unknownCLIVersion) {
+			log.Printf("Skipping %s (version: %s), @expo/cli does not support %s mode", cli.Source, cli.Version, EjectModeEject)
+			continue
+		}
+		return cli, true
+	}
+	return ExpoCLI{}, false
+}
+
+// versionCommand returns the command printing the CLI's version.
+func (e Expo) versionCommand(cli ExpoCLI) Command {
+	return Command{
+		Name: cli.Command[0],
+		Args: append(append([]string{}, cli.Command[1:]...), "--version"),
+		Dir:  e.Workdir,
+	}
+}
+
+// expoCLIVersion returns the version printed by the Expo CLI, or unknown if it can not be determined.
+func (e Expo) expoCLIVersion(ctx context.Context, cli ExpoCLI) string {
+	cmd := e.versionCommand(cli)
+
+	var out string
+	err := e.inPhase(ctx, PhaseInstall, func(ctx context.Context) error {
+		var err error
+		out, err = e.Runner.Output(ctx, cmd)
+		return err
+	})
+	if err != nil {
+		log.Warnf("Failed to get Expo CLI version: %s", err)
+		return unknownCLIVersion
+	}
+	lines := strings.Split(out, "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
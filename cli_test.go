@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeExecutable creates an empty executable, found by exec.LookPath.
+func writeExecutable(t *testing.T, pth string) {
+	if err := os.MkdirAll(filepath.Dir(pth), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(pth, nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolveExpoCLI(t *testing.T) {
+	tests := []struct {
+		name  string
+		mode  EjectMode
+		files map[string]string
+		// globalExpo puts an expo binary to the PATH.
+		globalExpo bool
+		version    string
+		outputs    map[string]string
+		errors     map[string]error
+		wantCLI    []string
+		wantSource string
+		want       []string
+	}{
+		{
+			name:       "project-local expo in prebuild mode",
+			mode:       EjectModePrebuild,
+			files:      map[string]string{"node_modules/.bin/expo": ""},
+			outputs:    map[string]string{"${workdir}/node_modules/.bin/expo --version": "0.10.16"},
+			wantCLI:    []string{"${workdir}/node_modules/.bin/expo"},
+			wantSource: "project-local node_modules/.bin/expo",
+			want:       []string{"${workdir}/node_modules/.bin/expo --version"},
+		},
+		{
+			name:       "npx in prebuild mode",
+			mode:       EjectModePrebuild,
+			wantCLI:    []string{"npx", "expo"},
+			wantSource: "npx",
+			want:       []string{"npx expo --version"},
+		},
+		{
+			name:       "project-local @expo/cli skipped in eject mode",
+			mode:       EjectModeEject,
+			files:      map[string]string{"node_modules/.bin/expo": ""},
+			globalExpo: true,
+			version:    "latest",
+			outputs:    map[string]string{"${workdir}/node_modules/.bin/expo --version": "0.10.16", "${path}/expo --version": "4.13.0"},
+			wantCLI:    []string{"${path}/expo"},
+			wantSource: "global expo-cli",
+			want:       []string{"${workdir}/node_modules/.bin/expo --version", "${path}/expo --version"},
+		},
+		{
+			name:       "project-local CLI of unknown version skipped in eject mode",
+			mode:       EjectModeEject,
+			files:      map[string]string{"node_modules/.bin/expo": ""},
+			globalExpo: true,
+			version:    "latest",
+			outputs:    map[string]string{"${path}/expo --version": "4.13.0"},
+			errors:     map[string]error{"${workdir}/node_modules/.bin/expo --version": errors.New("exit status 1")},
+			wantCLI:    []string{"${path}/expo"},
+			wantSource: "global expo-cli",
+			want:       []string{"${workdir}/node_modules/.bin/expo --version", "${path}/expo --version"},
+		},
+		{
+			name:       "global expo-cli of the selected version",
+			mode:       EjectModeEject,
+			globalExpo: true,
+			version:    "4.13.0",
+			outputs:    map[string]string{"${path}/expo --version": "4.13.0"},
+			wantCLI:    []string{"${path}/expo"},
+			wantSource: "global expo-cli",
+			want:       []string{"${path}/expo --version"},
+		},
+		{
+			name:       "global expo-cli of another version is reinstalled",
+			mode:       EjectModeEject,
+			globalExpo: true,
+			version:    "3.28.6",
+			outputs:    map[string]string{"${path}/expo --version": "4.13.0"},
+			wantCLI:    []string{"${path}/expo"},
+			wantSource: "installed global expo-cli",
+			want:       []string{"${path}/expo --version", "npm install -g expo-cli@3.28.6", "${path}/expo --version"},
+		},
+		{
+			name:       "expo-cli installed if missing",
+			mode:       EjectModeEject,
+			version:    "latest",
+			outputs:    map[string]string{"${path}/expo --version": "4.13.0"},
+			wantCLI:    []string{"${path}/expo"},
+			wantSource: "installed global expo-cli",
+			want:       []string{"npm install -g expo-cli", "${path}/expo --version"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workdir := createProject(t, nil)
+			for name := range tt.files {
+				writeExecutable(t, filepath.Join(workdir, name))
+			}
+			pathDir := createProject(t, nil)
+			t.Setenv("PATH", pathDir)
+			if tt.globalExpo {
+				writeExecutable(t, filepath.Join(pathDir, "expo"))
+			}
+
+			expand := func(s string) string {
+				return os.Expand(s, func(key string) string {
+					return map[string]string{"workdir": workdir, "path": pathDir}[key]
+				})
+			}
+			expandAll := func(lines []string) []string {
+				var expanded []string
+				for _, line := range lines {
+					expanded = append(expanded, expand(line))
+				}
+				return expanded
+			}
+
+			runner := newFakeCommandRunner()
+			for prefix, out := range tt.outputs {
+				runner.outputs[expand(prefix)] = out
+			}
+			for prefix, err := range tt.errors {
+				runner.errors[expand(prefix)] = err
+			}
+			runner.hooks["npm install -g"] = func(cmd Command) {
+				writeExecutable(t, filepath.Join(pathDir, "expo"))
+			}
+
+			e := newTestExpo(workdir, runner)
+			e.Mode = tt.mode
+			e.Version = tt.version
+			cli, err := e.resolveExpoCLI(context.Background())
+			if err != nil {
+				t.Fatalf("resolveExpoCLI() error = %v", err)
+			}
+
+			if want := expandAll(tt.wantCLI); !reflect.DeepEqual(cli.Command, want) || cli.Source != tt.wantSource {
+				t.Errorf("resolveExpoCLI() = %v (%s), want %v (%s)", cli.Command, cli.Source, want, tt.wantSource)
+			}
+			if got, want := runner.lines(), expandAll(tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("commands = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestResolveExpoCLI_DryRunPlansNpxVersion(t *testing.T) {
+	workdir := createProject(t, nil)
+	t.Setenv("PATH", createProject(t, nil))
+
+	runner := newFakeCommandRunner()
+	e := newTestExpo(workdir, runner)
+	e.Mode = EjectModePrebuild
+	e.Plan = NewPlan()
+
+	cli, err := e.resolveExpoCLI(context.Background())
+	if err != nil {
+		t.Fatalf("resolveExpoCLI(context.Background()) error = %v", err)
+	}
+	if cli.Version != unknownCLIVersion {
+		t.Errorf("version = %q, want %q", cli.Version, unknownCLIVersion)
+	}
+	if len(runner.commands) != 0 {
+		t.Errorf("commands = %v, want none", runner.lines())
+	}
+	if want := []string{`$ npx "expo" "--version" (in ` + workdir + ")"}; !reflect.DeepEqual(e.Plan.steps, want) {
+		t.Errorf("plan = %v, want %v", e.Plan.steps, want)
+	}
+}
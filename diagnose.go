@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// knownFailure is a failure signature of the Expo CLI or the package managers, with its explanation and fix.
+type knownFailure struct {
+	ID          string
+	Explanation string
+	Fix         string
+	pattern     *regexp.Regexp
+}
+
+// String ...
+func (f knownFailure) String() string {
+	return fmt.Sprintf("%s\nSuggested fix: %s", f.Explanation, f.Fix)
+}
+
+// knownFailures is the catalogue of the recognized failures, the first matching one is reported.
+var knownFailures = []knownFailure{
+	{
+		ID:          "node-too-old",
+		Explanation: "The installed Node.js version is too old for the Expo CLI or for the project's dependencies.",
+		Fix:         "Install a newer Node.js version (for example with nvm or asdf) in a Step before this Step, or select a newer stack.",
+		pattern:     regexp.MustCompile(`(?i)engine "node" is incompatible|Unsupported engine.*"node"|Node\.js (?:version )?v?[\d.]+ is (?:no longer |not )supported|requires Node(?:\.js)? (?:version )?>=?\s*v?\d+|SyntaxError: Unexpected token '\?'`),
+	},
+	{
+		ID:          "npm-global-eacces",
+		Explanation: "npm has no permission to write the global install prefix, so the global expo-cli can not be installed.",
+		Fix:         "Add expo-cli (or expo for SDK 46+) to the project's dependencies, so the project-local Expo CLI is used, or set a user-writable global prefix with `npm config set prefix ~/.npm-global`.",
+		pattern:     regexp.MustCompile(`(?s)npm ERR! code EACCES.*(?:/lib/node_modules|npm-global)|EACCES: permission denied, (?:mkdir|access|rename|symlink) '[^']*/lib/node_modules`),
+	},
+	{
+		ID:          "invalid-credentials",
+		Explanation: "Expo rejected the provided credentials.",
+		Fix:         "Check the user_name and password (use the username, not the e-mail address) or the access_token Secrets. Access tokens can be revoked or expire, create a new one on expo.dev if needed.",
+		pattern:     regexp.MustCompile(`(?i)invalid (?:username or password|credentials|access token)|incorrect (?:username or password|password)|INVALID_USERNAME_PASSWORD|UNAUTHORIZED_ERROR|\b401\b.*https://(?:api|exp)\.expo\.(?:dev|io)|https://(?:api|exp)\.expo\.(?:dev|io)\S*.*\b401\b|Your (?:access token|session) (?:is invalid|has expired)`),
+	},
+	{
+		ID:          "unsupported-sdk",
+		Explanation: "The Expo CLI does not support the project's Expo SDK version.",
+		Fix:         "Set expo_cli_verson to a version supporting the project's SDK, or set auto_align_versions to \"yes\". Expo SDK 46+ projects are generated with `expo prebuild` using the project-local @expo/cli.",
+		pattern:     regexp.MustCompile(`(?i)SDK (?:version )?v?[\d.]+ is (?:no longer |not )supported|unsupported SDK version|sdkVersion .* is not supported|expo eject is not supported|expo-cli does not support Expo SDK|This command is not supported in SDK`),
+	},
+	{
+		ID:          "missing-bundle-identifier",
+		Explanation: "The iOS bundle identifier is not set in the Expo config, it can not be prompted for in a non-interactive build.",
+		Fix:         "Set expo.ios.bundleIdentifier in app.json (or return it from app.config.js), for example \"com.company.app\".",
+		pattern:     regexp.MustCompile(`(?i)ios\.bundleIdentifier'? is (?:not found|missing|required)|specify (?:an |your )?iOS bundle identifier|ios\.bundleIdentifier.*must be (?:set|specified)|bundle identifier (?:is )?(?:not set|missing|required)`),
+	},
+	{
+		ID:          "missing-android-package",
+		Explanation: "The Android package name is not set in the Expo config, it can not be prompted for in a non-interactive build.",
+		Fix:         "Set expo.android.package in app.json (or return it from app.config.js), for example \"com.company.app\".",
+		pattern:     regexp.MustCompile(`(?i)android\.package'? is (?:not found|missing|required)|specify (?:an |your )?Android package|android\.package.*must be (?:set|specified)`),
+	},
+	{
+		ID:          "package-not-found",
+		Explanation: "A package or package version was not found in the npm registry.",
+		Fix:         "Check the package names and versions in dependency_overrides and override_react_native_version, and expo_cli_verson. Private packages need the npm_registry or npm_scoped_registries and the npm_auth_token inputs.",
+		pattern:     regexp.MustCompile(`npm ERR! code E404|npm ERR! code ETARGET|ERR_PNPM_FETCH_404|ERR_PNPM_NO_MATCHING_VERSION|Request failed \\?"404 Not Found\\?"|Couldn't find any versions for|No matching version found for`),
+	},
+	{
+		ID:          "registry-auth",
+		Explanation: "The npm registry rejected the package install: the registry requires authentication, or the token is invalid.",
+		Fix:         "Set npm_auth_token to a token with read access to the registries of npm_registry and npm_scoped_registries. The token is only sent to the configured registries, check that the private packages' scopes are listed.",
+		pattern:     regexp.MustCompile(`npm (?:ERR!|error) code E40[13]|ERR_PNPM_FETCH_40[13]|Request failed \\?"40[13] (?:Unauthorized|Forbidden)\\?"|YN0041|Invalid authentication \(as an? `),
+	},
+}
+
+// diagnose returns the known failure matching the failed command's output.
+func diagnose(out string) (knownFailure, bool) {
+	for _, failure := range knownFailures {
+		if failure.pattern.MatchString(out) {
+			return failure, true
+		}
+	}
+	return knownFailure{}, false
+}
+
+// diagnosedError is a command failure with the explanation and fix of the matching known failure.
+type diagnosedError struct {
+	err     error
+	failure knownFailure
+}
+
+// Error ...
+func (e diagnosedError) Error() string {
+	return fmt.Sprintf("%s\n\n%s", e.err, e.failure)
+}
+
+// diagnoseError adds the explanation and fix of the known failure matching the output to the command's error.
+// Killed commands are not diagnosed, their output is incomplete.
+func diagnoseError(err error, out string) error {
+	if err == nil {
+		return nil
+	}
+	switch err.(type) {
+	case *CommandKilledError, *PhaseTimeoutError:
+		return err
+	}
+	if failure, ok := diagnose(out); ok {
+		return diagnosedError{err: err, failure: failure}
+	}
+	return err
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestDiagnose_RecordedOutputs checks the catalogue against the recorded command outputs in testdata/diagnose,
+// each file is named after the known failure it should be diagnosed as, optionally followed by a variant
+// (like registry-auth.yarn.txt). The unknown outputs should not match.
+func TestDiagnose_RecordedOutputs(t *testing.T) {
+	pths, err := filepath.Glob(filepath.Join("testdata", "diagnose", "*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pths) == 0 {
+		t.Fatal("no recorded outputs found")
+	}
+
+	diagnosed := map[string]bool{}
+	for _, pth := range pths {
+		name := strings.TrimSuffix(filepath.Base(pth), ".txt")
+		want := strings.SplitN(name, ".", 2)[0]
+		t.Run(name, func(t *testing.T) {
+			out, err := ioutil.ReadFile(pth)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			failure, ok := diagnose(string(out))
+			if want == "unknown" {
+				if ok {
+					t.Errorf("diagnose() = %s, want no match", failure.ID)
+				}
+				return
+			}
+			if !ok || failure.ID != want {
+				t.Errorf("diagnose() = %q, %v, want %q", failure.ID, ok, want)
+			}
+			diagnosed[failure.ID] = true
+		})
+	}
+
+	for _, failure := range knownFailures {
+		if !diagnosed[failure.ID] {
+			t.Errorf("no recorded output for %s", failure.ID)
+		}
+	}
+}
+
+func TestDiagnoseError(t *testing.T) {
+	out := "Error: SDK version 37.0.0 is no longer supported."
+
+	err := diagnoseError(errors.New("exit status 1"), out)
+	if want := "exit status 1\n\nThe Expo CLI does not support the project's Expo SDK version.\nSuggested fix: "; !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("diagnoseError() = %q, want prefix %q", err, want)
+	}
+
+	killed := &CommandKilledError{Cmd: NewCommand("expo", "eject"), Err: errors.New("context canceled")}
+	if err := diagnoseError(killed, out); err != killed {
+		t.Errorf("diagnoseError() = %v, want the killed error", err)
+	}
+
+	if err := diagnoseError(nil, out); err != nil {
+		t.Errorf("diagnoseError() = %v, want nil", err)
+	}
+}
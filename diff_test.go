@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   string
+	}{
+		{name: "equal", before: "a\nb\n", after: "a\nb\n", want: ""},
+		{
+			name:   "changed line",
+			before: "a\nb\nc\n",
+			after:  "a\nB\nc\n",
+			want:   "--- a/p\n+++ b/p\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name:   "new file",
+			before: "",
+			after:  "a\nb\n",
+			want:   "--- a/p\n+++ b/p\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name:   "deleted content",
+			before: "a\n",
+			after:  "",
+			want:   "--- a/p\n+++ b/p\n@@ -1,1 +0,0 @@\n-a\n",
+		},
+		{
+			name:   "added trailing newline",
+			before: "a\nb",
+			after:  "a\nb\n",
+			want:   "--- a/p\n+++ b/p\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		{
+			name:   "removed trailing newline",
+			before: "a\n",
+			after:  "a",
+			want:   "--- a/p\n+++ b/p\n@@ -1,1 +1,1 @@\n-a\n+a\n\\ No newline at end of file\n",
+		},
+		{
+			name:   "separate hunks",
+			before: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			after:  "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n",
+			want: "--- a/p\n+++ b/p\n" +
+				"@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n" +
+				"@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+twelve\n",
+		},
+		{
+			name:   "insert after the first line",
+			before: "1\n2\n3\n4\n5\n6\n",
+			after:  "1\nnew\n2\n3\n4\n5\n6\n",
+			want:   "--- a/p\n+++ b/p\n@@ -1,4 +1,5 @@\n 1\n+new\n 2\n 3\n 4\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unifiedDiff("p", tt.before, tt.after); got != tt.want {
+				t.Errorf("unifiedDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
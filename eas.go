@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+)
+
+// PublishMethod selects how the project's JavaScript is published.
+type PublishMethod string
+
+const (
+	// PublishMethodClassic runs the classic `expo publish`.
+	PublishMethodClassic PublishMethod = "classic"
+	// PublishMethodEASUpdate runs `eas update`, the successor of the classic updates.
+	PublishMethodEASUpdate PublishMethod = "eas-update"
+)
+
+const (
+	easUpdateIOSGroupIDKey     = "EXPO_EAS_UPDATE_IOS_GROUP_ID"
+	easUpdateAndroidGroupIDKey = "EXPO_EAS_UPDATE_ANDROID_GROUP_ID"
+)
+
+// EASUpdateOptions ...
+type EASUpdateOptions struct {
+	Branch   string
+	Message  string
+	Platform Platform
+}
+
+// EASUpdate is an update of a platform, as printed by `eas update --json`.
+type EASUpdate struct {
+	ID             string `json:"id"`
+	Group          string `json:"group"`
+	Platform       string `json:"platform"`
+	RuntimeVersion string `json:"runtimeVersion"`
+	Branch         string `json:"branch"`
+}
+
+// resolveEASCLI looks for the project-local eas-cli first, then for a global one, and falls back to npx.
+func (e Expo) resolveEASCLI(ctx context.Context) ExpoCLI {
+	local := filepath.Join(e.Workdir, "node_modules", ".bin", "eas")
+	if exist, err := pathutil.IsPathExists(local); err != nil {
+		log.Warnf("Failed to check if %s exists: %s", local, err)
+	} else if exist {
+		cli := ExpoCLI{Command: []string{local}, Source: "project-local node_modules/.bin/eas"}
+		cli.Version = e.expoCLIVersion(ctx, cli)
+		return cli
+	}
+
+	if pth, err := exec.LookPath("eas"); err == nil {
+		cli := ExpoCLI{Command: []string{pth}, Source: "global eas-cli"}
+		cli.Version = e.expoCLIVersion(ctx, cli)
+		return cli
+	}
+
+	// eas-cli is not a project dependency, npx installs it on the fly.
+	return ExpoCLI{Command: []string{"npx", "--yes", "eas-cli"}, Version: "latest", Source: "npx"}
+}
+
+// easCommand returns a command for the resolved eas-cli.
+// eas-cli authenticates with EXPO_TOKEN or with the session stored by `expo login`.
+func (e Expo) easCommand(args ...string) Command {
+	cli := []string{"eas"}
+	if len(e.EAS.Command) > 0 {
+		cli = e.EAS.Command
+	}
+	return Command{
+		Name: cli[0],
+		Args: append(append([]string{}, cli[1:]...), args...),
+		Dir:  e.Workdir,
+		Envs: e.envs(),
+	}
+}
+
+// easUpdate publishes an EAS Update and returns the `eas update` output.
+func (e Expo) easUpdate(ctx context.Context, opts EASUpdateOptions) (string, error) {
+	args := []string{"update", "--branch", opts.Branch, "--message", opts.Message, "--non-interactive", "--json"}
+	if opts.Platform != "" {
+		args = append(args, "--platform", string(opts.Platform))
+	}
+
+	cmd := e.easCommand(args...)
+
+	log.Donef("$ %s", cmd)
+	var out string
+	err := e.inPhase(ctx, PhasePublish, func(ctx context.Context) error {
+		var err error
+		out, err = e.Retry.Do(ctx, func() (string, error) {
+			return e.Runner.RunAndCapture(ctx, cmd)
+		})
+		return diagnoseError(err, out)
+	})
+	return out, err
+}
+
+// parseEASUpdateOutput returns the update group IDs by platform from the `eas update --json` output.
+// The output is the combined stdout and stderr, the JSON array is mixed with progress logs and warnings,
+// which may contain brackets too: the last array decoding to updates is used.
+func parseEASUpdateOutput(out string) (map[string]string, error) {
+	for end := len(out); end > 0; {
+		start := strings.LastIndex(out[:end], "[")
+		if start == -1 {
+			break
+		}
+		end = start
+
+		var updates []EASUpdate
+		if err := json.NewDecoder(strings.NewReader(out[start:])).Decode(&updates); err != nil {
+			continue
+		}
+		groupIDs := map[string]string{}
+		for _, update := range updates {
+			if update.Platform != "" && update.Group != "" {
+				groupIDs[update.Platform] = update.Group
+			}
+		}
+		if len(groupIDs) > 0 {
+			return groupIDs, nil
+		}
+	}
+	return nil, fmt.Errorf("no update groups in the output")
+}
+
+// exportEASUpdateGroupIDs exports the update group ID of each published platform.
+func exportEASUpdateGroupIDs(groupIDs map[string]string) error {
+	return exportOutputs(
+		output{easUpdateIOSGroupIDKey, groupIDs[string(PlatformIOS)]},
+		output{easUpdateAndroidGroupIDKey, groupIDs[string(PlatformAndroid)]},
+	)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestExpoEASUpdate(t *testing.T) {
+	runner := newFakeCommandRunner()
+	e := newTestExpo("/project", runner)
+	e.Token = "token"
+	e.EAS = ExpoCLI{Command: []string{"npx", "--yes", "eas-cli"}}
+
+	if _, err := e.easUpdate(context.Background(), EASUpdateOptions{Branch: "main", Message: "Fix login", Platform: PlatformIOS}); err != nil {
+		t.Fatalf("easUpdate() error = %v", err)
+	}
+
+	want := []string{"npx --yes eas-cli update --branch main --message Fix login --non-interactive --json --platform ios"}
+	if got := runner.lines(); !reflect.DeepEqual(got, want) {
+		t.Errorf("commands = %v, want %v", got, want)
+	}
+	if got := runner.commands[0].Envs; !reflect.DeepEqual(got, []string{"EXPO_TOKEN=token"}) {
+		t.Errorf("envs = %v", got)
+	}
+}
+
+func TestParseEASUpdateOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "both platforms",
+			out: `- Exporting...
+✔ Exported bundle(s)
+[{"id":"a1","group":"g-1","platform":"android","runtimeVersion":"1.0.0","branch":"main"},{"id":"i1","group":"g-1","platform":"ios","runtimeVersion":"1.0.0","branch":"main"}]`,
+			want: map[string]string{"ios": "g-1", "android": "g-1"},
+		},
+		{
+			name: "bracketed logs around the JSON",
+			out: `[expo-cli] Using the project-local eas-cli
+[{"id":"a1","group":"g-3","platform":"android","runtimeVersion":"1.0.0","branch":"main","assets":[{"key":"k1"}]}]
+[warn] A newer version of eas-cli is available`,
+			want: map[string]string{"android": "g-3"},
+		},
+		{
+			name: "single platform",
+			out:  `[{"id":"i1","group":"g-2","platform":"ios"}]`,
+			want: map[string]string{"ios": "g-2"},
+		},
+		{
+			name:    "no JSON",
+			out:     "Error: branch not found",
+			wantErr: true,
+		},
+		{
+			name:    "only bracketed logs",
+			out:     "[1/2] Exporting\n[2/2] Uploading",
+			wantErr: true,
+		},
+		{
+			name:    "no updates",
+			out:     "[]",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEASUpdateOutput(tt.out)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseEASUpdateOutput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseEASUpdateOutput() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
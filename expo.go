@@ -1,22 +1,126 @@
 package main
 
 import (
-	"os"
+	"context"
+	"path/filepath"
 	"strings"
+	"time"
 
-	"github.com/bitrise-io/go-utils/command"
 	"github.com/bitrise-io/go-utils/log"
 	"github.com/bitrise-tools/go-steputils/stepconf"
 )
 
+// EjectMode selects how the native projects are generated.
+type EjectMode string
+
+const (
+	// EjectModeEject runs the classic `expo eject` via the globally installed expo-cli.
+	EjectModeEject EjectMode = "eject"
+	// EjectModePrebuild runs `expo prebuild` via the project-local @expo/cli.
+	EjectModePrebuild EjectMode = "prebuild"
+)
+
 // Expo ...
 type Expo struct {
 	Version string
 	Workdir string
+	// SDKVersion is the Expo SDK major version of the project, 0 if unknown.
+	SDKVersion int
+	Mode       EjectMode
+	// Token is an Expo access token, passed to the Expo CLI as EXPO_TOKEN.
+	Token stepconf.Secret
+	// HomeDir overrides the Expo home directory, which stores the login session, if set.
+	HomeDir string
+	// Registry is the npm registry config of the package installs.
+	Registry NPMRegistry
+	// CLI is the resolved Expo CLI, see resolveExpoCLI.
+	CLI ExpoCLI
+	// EAS is the resolved eas-cli, see resolveEASCLI.
+	EAS ExpoCLI
+	// AppConfig is the project's resolved Expo config, see resolveExpoConfig.
+	AppConfig ExpoConfig
+	// Runner runs the commands.
+	Runner CommandRunner
+	// Retry is the retry policy of the network bound commands.
+	Retry RetryPolicy
+	// Timeouts are the phase timeouts, a phase without a timeout is not limited.
+	Timeouts map[Phase]time.Duration
+	// Plan collects the file changes instead of writing them, if set (dry run).
+	Plan *Plan
+}
+
+// Platform selects the native projects to generate.
+type Platform string
+
+const (
+	// PlatformAll ...
+	PlatformAll Platform = "all"
+	// PlatformIOS ...
+	PlatformIOS Platform = "ios"
+	// PlatformAndroid ...
+	PlatformAndroid Platform = "android"
+)
+
+// IOS reports whether the iOS project is selected.
+func (p Platform) IOS() bool {
+	return p == PlatformAll || p == PlatformIOS || p == ""
+}
+
+// Android reports whether the Android project is selected.
+func (p Platform) Android() bool {
+	return p == PlatformAll || p == PlatformAndroid || p == ""
+}
+
+// EjectOptions ...
+type EjectOptions struct {
+	Platform Platform
+	// Clean deletes the native directories before generating them.
+	Clean bool
+	// Template is an npm package or a local tarball used as the native project template.
+	Template string
+	// SkipDependencyInstall skips installing npm packages and CocoaPods.
+	SkipDependencyInstall bool
+}
+
+// envs returns the environment variables to pass to every expo, eas and package manager subprocess.
+func (e Expo) envs() []string {
+	var envs []string
+	if e.Token != "" {
+		envs = append(envs, "EXPO_TOKEN="+string(e.Token))
+	}
+	if e.HomeDir != "" {
+		envs = append(envs, expoHomeDirEnvKey+"="+e.HomeDir)
+	}
+	if e.Registry.AuthToken != "" {
+		envs = append(envs, npmAuthTokenEnvKey+"="+string(e.Registry.AuthToken))
+	}
+	return envs
+}
+
+// expoCommand returns a command for the resolved Expo CLI, or the expo binary in the PATH if it is not resolved yet.
+func (e Expo) expoCommand(args ...string) Command {
+	cli := []string{"expo"}
+	if len(e.CLI.Command) > 0 {
+		cli = e.CLI.Command
+	}
+	return Command{
+		Name: cli[0],
+		Args: append(append([]string{}, cli[1:]...), args...),
+		Dir:  e.Workdir,
+		Envs: e.envs(),
+	}
+}
+
+// runRetrying runs the command with the retry policy, its output is streamed and captured for the failure classification.
+func (e Expo) runRetrying(ctx context.Context, cmd Command) error {
+	out, err := e.Retry.Do(ctx, func() (string, error) {
+		return e.Runner.RunAndCapture(ctx, cmd)
+	})
+	return diagnoseError(err, out)
 }
 
 // installExpoCLI runs the install npm command to install the expo-cli
-func (e Expo) installExpoCLI() error {
+func (e Expo) installExpoCLI(ctx context.Context) error {
 	args := []string{"install", "-g"}
 	if e.Version != "latest" {
 		args = append(args, "expo-cli@"+e.Version)
@@ -24,64 +128,128 @@ func (e Expo) installExpoCLI() error {
 		args = append(args, "expo-cli")
 	}
 
-	cmd := command.New("npm", args...)
-	cmd.SetStdout(os.Stdout)
-	cmd.SetStderr(os.Stderr)
+	cmd := NewCommand("npm", args...)
+	cmd.Envs = e.envs()
+	if e.Registry.configured() {
+		// The project .npmrc is not read by global installs.
+		cmd.Envs = append(cmd.Envs, "npm_config_userconfig="+filepath.Join(e.Workdir, ".npmrc"))
+	}
 
-	log.Donef("$ %s", cmd.PrintableCommandArgs())
-	return cmd.Run()
+	log.Donef("$ %s", cmd)
+	return e.inPhase(ctx, PhaseInstall, func(ctx context.Context) error {
+		return e.runRetrying(ctx, cmd)
+	})
 }
 
 // Login with your Expo account
-func (e Expo) login(userName string, password stepconf.Secret) error {
-	args := []string{"login", "--non-interactive", "-u", userName, "-p", string(password)}
+func (e Expo) login(ctx context.Context, userName string, password stepconf.Secret) error {
+	args := []string{"login"}
+	// @expo/cli rejects the unknown --non-interactive flag.
+	if e.Mode == EjectModeEject {
+		args = append(args, "--non-interactive")
+	}
+	args = append(args, "-u", userName, "-p", string(password))
 
-	cmd := command.New("expo", args...)
-	cmd.SetStdout(os.Stdout)
-	cmd.SetStderr(os.Stderr)
+	cmd := e.expoCommand(args...)
 
-	nonFilteredArgs := ("$ " + cmd.PrintableCommandArgs())
+	nonFilteredArgs := ("$ " + cmd.String())
 	fileredArgs := strings.Replace(nonFilteredArgs, string(password), "[REDACTED]", -1)
 	log.Printf(fileredArgs)
 
-	return cmd.Run()
+	return e.inPhase(ctx, PhaseLogin, func(ctx context.Context) error {
+		out, err := e.Runner.RunAndCapture(ctx, cmd)
+		return diagnoseError(err, out)
+	})
 }
 
 // Logout from your Expo account
-func (e Expo) logout() error {
-	cmd := command.New("expo", "logout", "--non-interactive")
-	cmd.SetStdout(os.Stdout)
-	cmd.SetStderr(os.Stderr)
+func (e Expo) logout(ctx context.Context) error {
+	args := []string{"logout"}
+	if e.Mode == EjectModeEject {
+		args = append(args, "--non-interactive")
+	}
+	cmd := e.expoCommand(args...)
 
-	log.Donef("$ %s", cmd.PrintableCommandArgs())
-	return cmd.Run()
+	log.Donef("$ %s", cmd)
+	return e.inPhase(ctx, PhaseLogin, func(ctx context.Context) error {
+		return e.Runner.Run(ctx, cmd)
+	})
 }
 
 // Eject command creates Xcode and Android Studio projects for your app.
-func (e Expo) eject() error {
+func (e Expo) eject(ctx context.Context, opts EjectOptions) error {
+	if e.Mode == EjectModePrebuild {
+		return e.prebuild(ctx, opts)
+	}
+
+	if opts.Platform != PlatformAll && opts.Platform != "" {
+		log.Warnf("expo eject generates both native projects, only the %s project is used", opts.Platform)
+	}
+
 	args := []string{"eject", "--non-interactive"}
 
-	cmd := command.New("expo", args...)
-	cmd.SetStdout(os.Stdout)
-	cmd.SetStderr(os.Stderr)
-	if e.Workdir != "" {
-		cmd.SetDir(e.Workdir)
+	cmd := e.expoCommand(args...)
+
+	log.Donef("$ %s", cmd)
+	return e.inPhase(ctx, PhaseEject, func(ctx context.Context) error {
+		return e.runRetrying(ctx, cmd)
+	})
+}
+
+// prebuild generates the native projects with `expo prebuild`, the successor of `expo eject`.
+func (e Expo) prebuild(ctx context.Context, opts EjectOptions) error {
+	args := []string{"prebuild"}
+	if opts.Platform != "" {
+		args = append(args, "--platform", string(opts.Platform))
+	}
+	if opts.Clean {
+		args = append(args, "--clean")
+	}
+	if opts.Template != "" {
+		args = append(args, "--template", opts.Template)
+	}
+	if opts.SkipDependencyInstall {
+		args = append(args, "--no-install")
 	}
 
-	log.Donef("$ %s", cmd.PrintableCommandArgs())
-	return cmd.Run()
+	cmd := e.expoCommand(args...)
+	// @expo/cli has no --non-interactive flag, it disables prompts in CI mode.
+	cmd.Envs = append(cmd.Envs, "CI=1")
+
+	log.Donef("$ %s", cmd)
+	return e.inPhase(ctx, PhaseEject, func(ctx context.Context) error {
+		return e.runRetrying(ctx, cmd)
+	})
 }
 
-func (e Expo) publish() error {
-	args := []string{"publish", "--non-interactive"}
+// PublishOptions ...
+type PublishOptions struct {
+	// ReleaseChannel is the release channel to publish to, the default channel if empty.
+	ReleaseChannel string
+	// Target is the native runtime the bundles are published for (managed or bare), detected by the Expo CLI if empty.
+	Target string
+}
 
-	cmd := command.New("expo", args...)
-	cmd.SetStdout(os.Stdout)
-	cmd.SetStderr(os.Stderr)
-	if e.Workdir != "" {
-		cmd.SetDir(e.Workdir)
+// publish publishes the project and returns the publish output.
+func (e Expo) publish(ctx context.Context, opts PublishOptions) (string, error) {
+	args := []string{"publish", "--non-interactive"}
+	if opts.ReleaseChannel != "" {
+		args = append(args, "--release-channel", opts.ReleaseChannel)
+	}
+	if opts.Target != "" {
+		args = append(args, "--target", opts.Target)
 	}
 
-	log.Donef("$ %s", cmd.PrintableCommandArgs())
-	return cmd.Run()
+	cmd := e.expoCommand(args...)
+
+	log.Donef("$ %s", cmd)
+	var out string
+	err := e.inPhase(ctx, PhasePublish, func(ctx context.Context) error {
+		var err error
+		out, err = e.Retry.Do(ctx, func() (string, error) {
+			return e.Runner.RunAndCapture(ctx, cmd)
+		})
+		return diagnoseError(err, out)
+	})
+	return out, err
 }
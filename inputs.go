@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/pathutil"
+)
+
+// inputRule is a constraint on the Step inputs, check returns nil if the inputs satisfy it.
+type inputRule struct {
+	name  string
+	check func(cfg Config) error
+}
+
+// InputValidationError lists every violated input rule.
+type InputValidationError struct {
+	Violations []string
+}
+
+// Error ...
+func (e InputValidationError) Error() string {
+	return fmt.Sprintf("%d input issue(s) found:\n- %s", len(e.Violations), strings.Join(e.Violations, "\n- "))
+}
+
+// inputRules are checked in order, the rules checking multiple inputs come after the single input rules.
+var inputRules = []inputRule{
+	{
+		name: "project_path contains package.json",
+		check: func(cfg Config) error {
+			pth := filepath.Join(cfg.Workdir, "package.json")
+			if exist, err := pathutil.IsPathExists(pth); err != nil {
+				return err
+			} else if !exist {
+				return fmt.Errorf("project_path (%s) does not contain a package.json file", cfg.Workdir)
+			}
+			return nil
+		},
+	},
+	{
+		name: "run_publish is yes or no",
+		check: func(cfg Config) error {
+			if cfg.RunPublish != "yes" && cfg.RunPublish != "no" {
+				return fmt.Errorf("run_publish (%s) should be yes or no", cfg.RunPublish)
+			}
+			return nil
+		},
+	},
+	{
+		name: "override_react_native_version is a version",
+		check: func(cfg Config) error {
+			if cfg.OverrideReactNativeVersion != "" && !isVersionSpec(cfg.OverrideReactNativeVersion) {
+				return fmt.Errorf("override_react_native_version (%s) should be a semver version, a range or a URL", cfg.OverrideReactNativeVersion)
+			}
+			return nil
+		},
+	},
+	{
+		name: "dependency_overrides are valid",
+		check: func(cfg Config) error {
+			// Only the dependency_overrides lines are checked, the SDK aligned versions come from the compatibility table.
+			_, err := dependencyOverrides(Config{DependencyOverrides: cfg.DependencyOverrides}, 0)
+			return err
+		},
+	},
+	{
+		name: "build_number is valid",
+		check: func(cfg Config) error {
+			return validateBuildNumber(cfg.BuildNumber)
+		},
+	},
+	{
+		name: "retry inputs are valid",
+		check: func(cfg Config) error {
+			if cfg.RetryAttempts < 1 {
+				return fmt.Errorf("retry_attempts (%d) should be at least 1", cfg.RetryAttempts)
+			}
+			if cfg.RetryDelay < 0 {
+				return fmt.Errorf("retry_delay (%d) should not be negative", cfg.RetryDelay)
+			}
+			return nil
+		},
+	},
+	{
+		name: "phase_timeouts are valid",
+		check: func(cfg Config) error {
+			_, err := parsePhaseTimeouts(cfg.PhaseTimeouts)
+			if err != nil {
+				return fmt.Errorf("invalid phase_timeouts: %s", err)
+			}
+			return nil
+		},
+	},
+	{
+		name: "npm registries are valid",
+		check: func(cfg Config) error {
+			if cfg.NPMRegistry != "" {
+				if err := validateRegistryURL(cfg.NPMRegistry); err != nil {
+					return fmt.Errorf("npm_registry: %s", err)
+				}
+			}
+			if _, err := parseScopedRegistries(cfg.NPMScopedRegistries); err != nil {
+				return fmt.Errorf("invalid npm_scoped_registries: %s", err)
+			}
+			return nil
+		},
+	},
+	{
+		name: "single authentication method",
+		check: func(cfg Config) error {
+			if cfg.AccessToken != "" && (cfg.UserName != "" || cfg.Password != "") {
+				return fmt.Errorf("access token is specified together with user name and password, provide only one of them")
+			}
+			return nil
+		},
+	},
+	{
+		name: "user name and password together",
+		check: func(cfg Config) error {
+			if cfg.UserName != "" && cfg.Password == "" {
+				return fmt.Errorf("user name is specified but password is not provided")
+			}
+			if cfg.UserName == "" && cfg.Password != "" {
+				return fmt.Errorf("password is specified but is not provided user name")
+			}
+			return nil
+		},
+	},
+	{
+		name: "publish requires credentials",
+		check: func(cfg Config) error {
+			if cfg.RunPublish == "yes" && cfg.AccessToken == "" && (cfg.UserName == "" || cfg.Password == "") {
+				return fmt.Errorf("run_publish is yes, but neither access_token nor user_name and password are provided")
+			}
+			return nil
+		},
+	},
+	{
+		name: "EAS Update requires a branch",
+		check: func(cfg Config) error {
+			if cfg.RunPublish == "yes" && cfg.PublishMethod == PublishMethodEASUpdate && cfg.UpdateBranch == "" {
+				return fmt.Errorf("update_branch is required if publish_method is %s", PublishMethodEASUpdate)
+			}
+			return nil
+		},
+	},
+}
+
+// validateConfig checks every input rule, and returns all violations at once.
+func validateConfig(cfg Config, rules []inputRule) error {
+	var violations []string
+	for _, rule := range rules {
+		if err := rule.check(cfg); err != nil {
+			violations = append(violations, err.Error())
+		}
+	}
+	if len(violations) > 0 {
+		return InputValidationError{Violations: violations}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateConfig(t *testing.T) {
+	dir := createProject(t, projectFiles)
+	emptyDir := createProject(t, nil)
+
+	valid := Config{Workdir: dir, RunPublish: "no", PublishMethod: PublishMethodClassic, RetryAttempts: 3, RetryDelay: 5}
+
+	tests := []struct {
+		name           string
+		modify         func(cfg *Config)
+		wantViolations []string
+	}{
+		{
+			name:   "valid",
+			modify: func(cfg *Config) {},
+		},
+		{
+			name: "publish with access token",
+			modify: func(cfg *Config) {
+				cfg.RunPublish = "yes"
+				cfg.AccessToken = "token"
+			},
+		},
+		{
+			name: "version range and URL",
+			modify: func(cfg *Config) {
+				cfg.OverrideReactNativeVersion = "https://github.com/expo/react-native/archive/sdk-39.0.0.tar.gz"
+				cfg.DependencyOverrides = "react@~16.13.1"
+			},
+		},
+		{
+			name: "publish without credentials",
+			modify: func(cfg *Config) {
+				cfg.RunPublish = "yes"
+			},
+			wantViolations: []string{"run_publish is yes, but neither access_token nor user_name and password are provided"},
+		},
+		{
+			name: "all violations reported",
+			modify: func(cfg *Config) {
+				cfg.Workdir = emptyDir
+				cfg.RunPublish = "true"
+				cfg.OverrideReactNativeVersion = "latest-ish"
+				cfg.UserName = "user"
+				cfg.AccessToken = "token"
+			},
+			wantViolations: []string{
+				"project_path (" + emptyDir + ") does not contain a package.json file",
+				"run_publish (true) should be yes or no",
+				"override_react_native_version (latest-ish) should be a semver version, a range or a URL",
+				"access token is specified together with user name and password, provide only one of them",
+				"user name is specified but password is not provided",
+			},
+		},
+		{
+			name: "EAS Update without branch",
+			modify: func(cfg *Config) {
+				cfg.RunPublish = "yes"
+				cfg.AccessToken = "token"
+				cfg.PublishMethod = PublishMethodEASUpdate
+			},
+			wantViolations: []string{"update_branch is required if publish_method is eas-update"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid
+			tt.modify(&cfg)
+
+			err := validateConfig(cfg, inputRules)
+			if len(tt.wantViolations) == 0 {
+				if err != nil {
+					t.Fatalf("validateConfig() error = %v", err)
+				}
+				return
+			}
+
+			validationErr, ok := err.(InputValidationError)
+			if !ok {
+				t.Fatalf("validateConfig() error = %v, want InputValidationError", err)
+			}
+			if !reflect.DeepEqual(validationErr.Violations, tt.wantViolations) {
+				t.Errorf("violations = %q, want %q", validationErr.Violations, tt.wantViolations)
+			}
+		})
+	}
+}
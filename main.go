@@ -1,194 +1,450 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
-	"github.com/bitrise-io/go-utils/command"
 	"github.com/bitrise-io/go-utils/errorutil"
-	"github.com/bitrise-io/go-utils/fileutil"
 	"github.com/bitrise-io/go-utils/log"
-	"github.com/bitrise-io/go-utils/pathutil"
 	"github.com/bitrise-tools/go-steputils/stepconf"
-	"github.com/bitrise-tools/xcode-project/serialized"
 )
 
 // Config ...
 type Config struct {
-	Workdir                    string          `env:"project_path,dir"`
-	ExpoCLIVersion             string          `env:"expo_cli_verson,required"`
-	UserName                   string          `env:"user_name"`
-	Password                   stepconf.Secret `env:"password"`
-	RunPublish                 string          `env:"run_publish"`
-	OverrideReactNativeVersion string          `env:"override_react_native_version"`
+	Workdir                    string                     `env:"project_path,dir"`
+	ExpoCLIVersion             string                     `env:"expo_cli_verson,required"`
+	UserName                   string                     `env:"user_name"`
+	Password                   stepconf.Secret            `env:"password"`
+	AccessToken                stepconf.Secret            `env:"access_token"`
+	RunPublish                 string                     `env:"run_publish"`
+	ReleaseChannel             string                     `env:"release_channel"`
+	PublishTarget              string                     `env:"publish_target,opt[auto,managed,bare]"`
+	PublishMethod              PublishMethod              `env:"publish_method,opt[classic,eas-update]"`
+	UpdateBranch               string                     `env:"update_branch"`
+	UpdateMessage              string                     `env:"update_message"`
+	OverrideReactNativeVersion string                     `env:"override_react_native_version"`
+	DependencyOverrides        string                     `env:"dependency_overrides"`
+	PackageManager             PackageManager             `env:"package_manager,opt[auto,npm,yarn,pnpm,bun]"`
+	AutoAlignVersions          string                     `env:"auto_align_versions,opt[yes,no]"`
+	DryRun                     string                     `env:"dry_run,opt[yes,no]"`
+	Platforms                  Platform                   `env:"platforms,opt[all,ios,android]"`
+	ExistingNativeDirs         ExistingNativeDirsStrategy `env:"existing_native_dirs,opt[skip,clean,fail]"`
+	AppVersion                 string                     `env:"app_version"`
+	BuildNumber                string                     `env:"build_number"`
+	BuildNumberOffset          int                        `env:"build_number_offset"`
+	IsolateExpoHome            string                     `env:"isolate_expo_home,opt[yes,no]"`
+	RetryAttempts              int                        `env:"retry_attempts"`
+	RetryDelay                 int                        `env:"retry_delay"`
+	PhaseTimeouts              string                     `env:"phase_timeouts"`
+	NodeVersionCheck           NodeVersionCheck           `env:"node_version_check,opt[fail,warn]"`
+	NPMRegistry                string                     `env:"npm_registry"`
+	NPMScopedRegistries        string                     `env:"npm_scoped_registries"`
+	NPMAuthToken               stepconf.Secret            `env:"npm_auth_token"`
 }
 
-func parsePackageJSON(pth string) (serialized.Object, error) {
-	b, err := fileutil.ReadBytesFromFile(pth)
-	if err != nil {
-		return nil, fmt.Errorf("Failed to read package.json file: %s", err)
-	}
+func failf(format string, v ...interface{}) {
+	log.Errorf(format, v...)
+	os.Exit(1)
+}
 
-	var packages serialized.Object
-	if err := json.Unmarshal(b, &packages); err != nil {
-		return nil, fmt.Errorf("Failed to parse package.json file: %s", err)
+func main() {
+	if err := run(); err != nil {
+		failf("%s", err)
 	}
-	return packages, nil
 }
 
-func savePackageJSON(packages serialized.Object, pth string) error {
-	b, err := json.MarshalIndent(packages, "", "  ")
-	if err != nil {
-		return fmt.Errorf("Failed to serialize modified package.json file: %s", err)
+// run runs the Step, the acquired resources (like the Expo session) are released on every exit path.
+func run() (err error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cleanup := &cleanupStack{}
+	stopSignalHandling := handleSignals(cancel)
+	defer stopSignalHandling()
+	defer cleanup.run()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Unexpected error: %v", r)
+		}
+	}()
+
+	var cfg Config
+	if err := stepconf.Parse(&cfg); err != nil {
+		return fmt.Errorf("Issue with input: %s", err)
 	}
 
-	if err := fileutil.WriteBytesToFile(pth, b); err != nil {
-		return fmt.Errorf("Failed to write modified package.json file: %s", err)
+	fmt.Println()
+	stepconf.Print(cfg)
+
+	if err := validateConfig(cfg, inputRules); err != nil {
+		return fmt.Errorf("Input validation failed: %s", err)
 	}
-	return nil
-}
 
-func validateUserNameAndpassword(userName string, password stepconf.Secret) error {
-	if userName != "" && string(password) == "" {
-		return fmt.Errorf("user name is specified but password is not provided")
+	fmt.Println()
+	log.Infof("Select eject mode")
+	// The Expo CLI is not available yet, the mode is selected based on the static config.
+	staticConfig, err := loadStaticExpoConfig(cfg.Workdir)
+	if err != nil {
+		return fmt.Errorf("Failed to read the Expo config: %s", err)
 	}
+	sdkVersion, err := staticConfig.SDKMajorVersion()
+	if err != nil {
+		log.Warnf("Failed to determine the Expo SDK version: %s", err)
+	} else {
+		log.Printf("Expo SDK version: %d", sdkVersion)
+		warnSDKDivergence(cfg.Workdir, sdkVersion)
+	}
+	mode := ejectModeForSDK(sdkVersion)
+	log.Donef("Eject mode: %s", mode)
 
-	if userName == "" && string(password) != "" {
-		return fmt.Errorf("password is specified but is not provided user name")
+	if _, err := dependencyOverrides(cfg, sdkVersion); err != nil {
+		return fmt.Errorf("Input validation failed: %s", err)
 	}
-	return nil
-}
 
-func failf(format string, v ...interface{}) {
-	log.Errorf(format, v...)
-	os.Exit(1)
-}
+	// Checked by the input rules.
+	timeouts, _ := parsePhaseTimeouts(cfg.PhaseTimeouts)
 
-func main() {
-	var cfg Config
-	if err := stepconf.Parse(&cfg); err != nil {
-		failf("Issue with input: %s", err)
+	expoCLIVersion := cfg.ExpoCLIVersion
+	if versions, ok := sdkCompatibility[sdkVersion]; ok && cfg.AutoAlignVersions == "yes" && expoCLIVersion == "latest" && versions.ExpoCLI != "" {
+		expoCLIVersion = versions.ExpoCLI
+		log.Printf("Using expo-cli version matching Expo SDK %d: %s", sdkVersion, expoCLIVersion)
 	}
 
-	fmt.Println()
-	stepconf.Print(cfg)
+	expo := Expo{
+		Version:    expoCLIVersion,
+		Workdir:    cfg.Workdir,
+		SDKVersion: sdkVersion,
+		Mode:       mode,
+		Token:      cfg.AccessToken,
+		Runner:     newDefaultCommandRunner(cfg.Password, cfg.AccessToken, cfg.NPMAuthToken),
+		Retry: RetryPolicy{
+			Attempts: cfg.RetryAttempts,
+			Delay:    time.Duration(cfg.RetryDelay) * time.Second,
+		},
+		Timeouts: timeouts,
+	}
+	if cfg.DryRun == "yes" {
+		log.Warnf("Dry run: the commands and file changes are only collected into a plan")
+		expo.Plan = NewPlan(cfg.Password, cfg.AccessToken, cfg.NPMAuthToken)
+		expo.Runner = dryRunCommandRunner{plan: expo.Plan, runner: expo.Runner}
+	}
 
-	if err := validateUserNameAndpassword(cfg.UserName, cfg.Password); err != nil {
-		failf("Input validation failed: %s", err)
+	if cfg.IsolateExpoHome == "yes" {
+		if expo.Plan != nil {
+			expo.Plan.AddNote("use a temporary Expo home directory")
+		} else {
+			homeDir, err := isolateExpoHome(cleanup)
+			if err != nil {
+				return err
+			}
+			expo.HomeDir = homeDir
+			log.Printf("Using temporary Expo home directory: %s", homeDir)
+		}
 	}
 
-	expo := Expo{
-		Version: cfg.ExpoCLIVersion,
-		Workdir: cfg.Workdir,
+	// Checked by the input rules.
+	scopedRegistries, _ := parseScopedRegistries(cfg.NPMScopedRegistries)
+	expo.Registry = NPMRegistry{URL: cfg.NPMRegistry, Scopes: scopedRegistries, AuthToken: cfg.NPMAuthToken}
+	if expo.Registry.configured() {
+		//
+		// Configure the private npm registry for the package installs
+		fmt.Println()
+		log.Infof("Configure npm registry")
+
+		manager, _, err := selectPackageManager(cfg.Workdir, cfg.PackageManager)
+		if err != nil {
+			return fmt.Errorf("Failed to select the node package manager: %s", err)
+		}
+		if err := writeRegistryConfig(cfg.Workdir, expo.Registry, manager == PackageManagerYarnBerry, expo.Plan, cleanup); err != nil {
+			return err
+		}
 	}
 
 	//
-	// Install expo-cli
+	// Check the Node.js and npm versions before installing the Expo CLI
 	fmt.Println()
-	log.Infof("Install Expo CLI version: %s", cfg.ExpoCLIVersion)
+	log.Infof("Preflight checks")
 	{
-		if err := expo.installExpoCLI(); err != nil {
-			failf("Failed to install the selected (%s) version for Expo CLI: %s", cfg.ExpoCLIVersion, err)
+		requirements := expoCLIRequirements(expo.Mode, expo.Version, expo.SDKVersion)
+		engines, err := engineRequirements(cfg.Workdir)
+		if err != nil {
+			return err
+		}
+		requirements = append(requirements, engines...)
+
+		issues := expo.checkNodeVersions(ctx, requirements)
+		if len(issues) == 0 {
+			log.Donef("The Node.js and npm versions meet the requirements")
+		} else if cfg.NodeVersionCheck == NodeVersionCheckWarn {
+			for _, issue := range issues {
+				log.Warnf("%s", issue)
+			}
+		} else {
+			return fmt.Errorf("Preflight checks failed:\n- %s\nInstall the required versions in a Step before this Step, or set node_version_check to %s", strings.Join(issues, "\n- "), NodeVersionCheckWarn)
 		}
 	}
 
 	//
-	// Logging in the user to the Expo account
-	loggedIn := false
-	if cfg.UserName != "" && cfg.Password != "" {
-		if err := login(expo, cfg); err != nil {
-			failf("Failed to log in to your provided Expo account: %s", err)
+	// Resolve the Expo CLI, installing expo-cli if needed
+	fmt.Println()
+	log.Infof("Resolve Expo CLI")
+	{
+		cli, err := expo.resolveExpoCLI(ctx)
+		if err != nil {
+			return fmt.Errorf("Failed to install the selected (%s) version for Expo CLI: %s", expo.Version, err)
 		}
-		loggedIn = true
+		expo.CLI = cli
+		log.Donef("Expo CLI: %s", cli)
 	}
 
-	if err := detach(expo, cfg); err != nil {
-		if loggedIn {
-			logout(expo)
+	//
+	// Resolve the Expo config, app.config.js and app.config.ts are evaluated by the Expo CLI
+	fmt.Println()
+	log.Infof("Resolve Expo config")
+	{
+		appConfig, err := expo.resolveExpoConfig(ctx)
+		if err != nil {
+			return fmt.Errorf("Failed to resolve the Expo config: %s", err)
+		}
+		expo.AppConfig = appConfig
+		log.Printf("Name: %s, slug: %s, SDK version: %s", appConfig.Name, appConfig.Slug, appConfig.SDKVersion)
+		if plugins := appConfig.Plugins(); len(plugins) > 0 {
+			log.Printf("Config plugins: %s", strings.Join(plugins, ", "))
 		}
-		failf(err.Error())
 	}
 
-	if loggedIn {
-		logout(expo)
+	if err := authenticatedDetach(ctx, expo, cfg, cleanup); err != nil {
+		return err
+	}
+
+	if expo.Plan != nil {
+		expo.Plan.AddNote("export the generated native project locations")
+		fmt.Println()
+		expo.Plan.Print()
+		return nil
 	}
-}
 
-func detach(e Expo, cfg Config) error {
 	//
-	// Eject project via the Expo CLI
+	// Export the generated native project locations
 	fmt.Println()
-	log.Infof("Eject project")
+	log.Infof("Export outputs")
 	{
-		if err := e.eject(); err != nil {
-			return fmt.Errorf("Failed to eject project: %s", err)
+		projects, err := findNativeProjects(cfg.Workdir, cfg.Platforms, expo.AppConfig)
+		if err != nil {
+			return fmt.Errorf("Failed to find the generated native projects: %s", err)
+		}
+		if err := exportNativeProjects(projects); err != nil {
+			return fmt.Errorf("Failed to export outputs: %s", err)
 		}
 	}
+	return nil
+}
+
+// authenticatedDetach logs in to the Expo account if credentials are provided, runs detach,
+// then logs out. The logout is registered in the cleanup, so it also runs on panics and signals.
+func authenticatedDetach(ctx context.Context, e Expo, cfg Config, cleanup *cleanupStack) error {
+	//
+	// Logging in the user to the Expo account, access tokens are passed to every command instead
+	if cfg.AccessToken != "" {
+		fmt.Println()
+		log.Infof("Using the provided Expo access token, skipping login")
+	} else if cfg.UserName != "" && cfg.Password != "" {
+		if err := login(ctx, e, cfg); err != nil {
+			return fmt.Errorf("Failed to log in to your provided Expo account: %s", err)
+		}
+
+		logoutNow := cleanup.push("log out from Expo", func() {
+			logout(e)
+		})
+		defer logoutNow()
+	}
+
+	return detach(ctx, e, cfg)
+}
 
+func detach(ctx context.Context, e Expo, cfg Config) error {
+	//
+	// Check for native directories of an already ejected project
 	fmt.Println()
-	log.Donef("Successfully ejected your project")
+	log.Infof("Check for existing native projects")
+	existing, err := detectExistingNativeProject(cfg.Workdir, cfg.Platforms)
+	if err != nil {
+		return fmt.Errorf("Failed to check for existing native projects: %s", err)
+	}
+
+	skipEject := false
+	opts := EjectOptions{Platform: cfg.Platforms}
+	// backup holds the native directories replaced by the regeneration, they are restored if it fails.
+	var backup nativeDirsBackup
+	regenerated := false
+	defer func() {
+		if regenerated || len(backup.dirs) == 0 {
+			return
+		}
+		if err := backup.restore(); err != nil {
+			log.Warnf("Failed to restore the native directories from %s: %s", backup.dir, err)
+			return
+		}
+		log.Printf("Restored the original native directories")
+	}()
+	if !existing.found() {
+		log.Printf("No existing native projects found")
+	} else {
+		log.Warnf("The project is already ejected: %s", existing)
+
+		switch cfg.ExistingNativeDirs {
+		case ExistingNativeDirsSkip:
+			log.Printf("existing_native_dirs is %s: using the existing native projects", ExistingNativeDirsSkip)
+			skipEject = true
+		case ExistingNativeDirsClean:
+			if existing.Bare {
+				return fmt.Errorf("existing_native_dirs is %s, but a bare workflow project can not be regenerated: app.json has no expo config", ExistingNativeDirsClean)
+			}
+			if backup, err = backupNativeDirs(cfg.Workdir, existing.Dirs, e.Plan); err != nil {
+				return err
+			}
+			log.Printf("existing_native_dirs is %s: backed up the native directories, regenerating them", ExistingNativeDirsClean)
+			opts.Clean = true
+		default:
+			return fmt.Errorf("existing_native_dirs is %s: the project is already ejected (%s)", ExistingNativeDirsFail, existing)
+		}
+	}
+
+	if cfg.AppVersion != "" || cfg.BuildNumber != "" {
+		//
+		// Set the version and build numbers in app.json
+		fmt.Println()
+		log.Infof("Set app version and build number")
+		if skipEject {
+			log.Warnf("The eject is skipped, the existing native projects will not pick up the app.json changes")
+		}
+		if err := injectAppVersions(e, cfg); err != nil {
+			return err
+		}
+	}
+
+	if !skipEject {
+		//
+		// Eject project via the Expo CLI
+		fmt.Println()
+		log.Infof("Eject project")
+		{
+			if err := e.eject(ctx, opts); err != nil {
+				return fmt.Errorf("Failed to eject project: %s", err)
+			}
+		}
+
+		fmt.Println()
+		log.Donef("Successfully ejected your project")
+
+		//
+		// Validate the generated native projects
+		fmt.Println()
+		log.Infof("Validate the generated native projects")
+		if e.Plan != nil {
+			e.Plan.AddNote("validate the generated native projects")
+		} else {
+			if err := validateNativeProjects(cfg.Workdir, cfg.Platforms, e.AppConfig); err != nil {
+				return err
+			}
+			log.Donef("The generated native projects are valid")
+		}
+
+		regenerated = true
+		if err := backup.remove(); err != nil {
+			log.Warnf("Failed to remove the native directories backup: %s", err)
+		}
+	}
 
 	if cfg.RunPublish == "yes" {
-		if err := runPublish(e, cfg); err != nil {
+		if err := runPublish(ctx, e, cfg); err != nil {
 			return fmt.Errorf("Failed to publish project: %s", err)
 		}
 	}
 
-	if cfg.OverrideReactNativeVersion != "" {
+	overrides, err := dependencyOverrides(cfg, e.SDKVersion)
+	if err != nil {
+		return err
+	}
+
+	if len(overrides) > 0 {
 		//
-		// Force certain version of React Native in package.json file
+		// Override dependency versions in package.json file
 		fmt.Println()
-		log.Infof("Set react-native dependency version: %s", cfg.OverrideReactNativeVersion)
+		log.Infof("Override dependencies in package.json")
 
 		packageJSONPth := filepath.Join(cfg.Workdir, "package.json")
 		packages, err := parsePackageJSON(packageJSONPth)
 		if err != nil {
 			return err
 		}
+		original := packages.Bytes()
 
-		deps, err := packages.Object("dependencies")
-		if err != nil {
-			return fmt.Errorf("Failed to parse dependencies from package.json file: %s", err)
+		if err := applyDependencyOverrides(packages, overrides); err != nil {
+			return err
 		}
 
-		deps["react-native"] = cfg.OverrideReactNativeVersion
-		packages["dependencies"] = deps
-
-		if err := savePackageJSON(packages, packageJSONPth); err != nil {
+		if e.Plan != nil {
+			e.Plan.AddFileChange("package.json", original, packages.Bytes())
+		} else if err := savePackageJSON(packages, packageJSONPth); err != nil {
 			return err
 		}
 
 		//
 		// Install new node dependencies
-		log.Printf("install new node dependencies")
+		fmt.Println()
+		log.Infof("Install new node dependencies")
 
-		nodeDepManager := "npm"
-		if exist, err := pathutil.IsPathExists(filepath.Join(cfg.Workdir, "yarn.lock")); err != nil {
-			log.Warnf("Failed to check if yarn.lock file exists in the workdir: %s", err)
-		} else if exist {
-			nodeDepManager = "yarn"
+		manager, reason, err := selectPackageManager(cfg.Workdir, cfg.PackageManager)
+		if err != nil {
+			return fmt.Errorf("Failed to select the node package manager: %s", err)
 		}
-
-		cmd := command.New(nodeDepManager, "install")
-		out, err := cmd.RunAndReturnTrimmedCombinedOutput()
+		log.Printf("Package manager: %s (%s)", manager, reason)
+
+		// package.json was modified, so the lockfile has to be updated.
+		args := manager.installArgs()
+		cmd := NewCommand(args[0], args[1:]...)
+		cmd.Dir = cfg.Workdir
+		cmd.Envs = e.envs()
+
+		log.Donef("$ %s", cmd)
+		var out string
+		err = e.inPhase(ctx, PhaseDependencyInstall, func(ctx context.Context) error {
+			var err error
+			out, err = e.Retry.Do(ctx, func() (string, error) {
+				return e.Runner.CombinedOutput(ctx, cmd)
+			})
+			return err
+		})
 		if err != nil {
+			switch err.(type) {
+			case *CommandKilledError, *PhaseTimeoutError:
+				// The command is named by the error already, its incomplete output is not diagnosed.
+				return err
+			}
 			if errorutil.IsExitStatusError(err) {
-				return fmt.Errorf("%s failed: %s", cmd.PrintableCommandArgs(), out)
+				return diagnoseError(fmt.Errorf("%s failed: %s", cmd, out), out)
 			}
-			return fmt.Errorf("%s failed: %s", cmd.PrintableCommandArgs(), err)
+			return diagnoseError(fmt.Errorf("%s failed: %s", cmd, err), out)
+		}
+
+		if cfg.Platforms.IOS() {
+			log.Warnf("The node dependencies changed, run CocoaPods install for the iOS project after this Step")
 		}
 	}
 
 	return nil
 }
 
-func login(expo Expo, cfg Config) error {
+func login(ctx context.Context, expo Expo, cfg Config) error {
 	fmt.Println()
 	log.Infof("Login to Expo")
 	{
-		return expo.login(cfg.UserName, cfg.Password)
+		return expo.login(ctx, cfg.UserName, cfg.Password)
 	}
 }
 
@@ -198,20 +454,76 @@ func logout(expo Expo) {
 	fmt.Println()
 	log.Infof("Logging out from Expo")
 	{
-		if err := expo.logout(); err != nil {
+		// The Step's context may be cancelled already, the logout has only its phase timeout.
+		if err := expo.logout(context.Background()); err != nil {
 			log.Warnf("Failed to log out from your Expo account: %s", err)
 		}
 	}
 }
 
-func runPublish(expo Expo, cfg Config) error {
+func runPublish(ctx context.Context, expo Expo, cfg Config) error {
+	if cfg.PublishMethod == PublishMethodEASUpdate {
+		return runEASUpdate(ctx, expo, cfg)
+	}
+
 	fmt.Println()
 	log.Infof("Running expo publish")
+	if expo.Mode == EjectModePrebuild {
+		log.Warnf("Classic updates are not supported by Expo SDK %d+, set publish_method to %s", prebuildMinSDKVersion, PublishMethodEASUpdate)
+	}
+
+	opts := PublishOptions{ReleaseChannel: cfg.ReleaseChannel}
+	if cfg.PublishTarget != "auto" {
+		opts.Target = cfg.PublishTarget
+	}
 
 	// Running publish
-	if err := expo.publish(); err != nil {
+	out, err := expo.publish(ctx, opts)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	if expo.Plan != nil {
+		expo.Plan.AddNote("export the published manifest and bundle URLs")
+		return nil
+	}
+
+	fmt.Println()
+	log.Infof("Export publish outputs")
+	result := parsePublishOutput(out)
+	if result.ManifestURL == "" {
+		log.Warnf("The manifest URL was not found in the expo publish output")
+	}
+	return exportPublishResult(result)
+}
+
+func runEASUpdate(ctx context.Context, expo Expo, cfg Config) error {
+	fmt.Println()
+	log.Infof("Running eas update")
+
+	expo.EAS = expo.resolveEASCLI(ctx)
+	log.Printf("eas-cli: %s", expo.EAS)
+
+	opts := EASUpdateOptions{
+		Branch:   cfg.UpdateBranch,
+		Message:  cfg.UpdateMessage,
+		Platform: cfg.Platforms,
+	}
+	out, err := expo.easUpdate(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	if expo.Plan != nil {
+		expo.Plan.AddNote("export the EAS Update group IDs")
+		return nil
+	}
+
+	fmt.Println()
+	log.Infof("Export EAS Update outputs")
+	groupIDs, err := parseEASUpdateOutput(out)
+	if err != nil {
+		return fmt.Errorf("Failed to parse the eas update output: %s", err)
+	}
+	return exportEASUpdateGroupIDs(groupIDs)
 }
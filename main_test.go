@@ -0,0 +1,370 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-io/go-utils/pathutil"
+)
+
+// projectFiles are the files of a minimal managed workflow project.
+var projectFiles = map[string]string{
+	"package.json": `{
+  "name": "app",
+  "dependencies": {
+    "expo": "~39.0.2",
+    "react-native": "0.63.2"
+  }
+}
+`,
+	"app.json": `{"expo": {"name": "app"}}`,
+}
+
+// nativeProjectFiles are the files generated by the eject.
+var nativeProjectFiles = map[string]string{
+	"android/app/src/main/AndroidManifest.xml": `<manifest package="com.app"/>`,
+	"ios/app.xcodeproj/project.pbxproj":        ``,
+}
+
+func createProject(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "steps-expo-detach")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Log(err)
+		}
+	})
+
+	writeFiles(t, dir, files)
+	return dir
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		pth := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(pth), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := fileutil.WriteStringToFile(pth, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// newEjectingCommandRunner returns a fake runner, which generates the native project files on eject.
+func newEjectingCommandRunner(t *testing.T) *fakeCommandRunner {
+	runner := newFakeCommandRunner()
+	eject := func(cmd Command) {
+		writeFiles(t, cmd.Dir, nativeProjectFiles)
+	}
+	runner.hooks["expo eject"] = eject
+	runner.hooks["npx expo prebuild"] = eject
+	return runner
+}
+
+func newTestExpo(workdir string, runner CommandRunner) Expo {
+	return Expo{
+		Version: "latest",
+		Workdir: workdir,
+		Mode:    EjectModeEject,
+		CLI:     ExpoCLI{Command: []string{"expo"}},
+		Runner:  runner,
+	}
+}
+
+func TestAuthenticatedDetach(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    EjectMode
+		cfg     Config
+		errors  map[string]error
+		files   map[string]string
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "login, eject, publish, logout",
+			cfg:  Config{UserName: "user", Password: "pass", RunPublish: "yes"},
+			want: []string{
+				"expo login --non-interactive -u user -p pass",
+				"expo eject --non-interactive",
+				"expo publish --non-interactive",
+				"expo logout --non-interactive",
+			},
+		},
+		{
+			name: "prebuild login and logout without --non-interactive",
+			mode: EjectModePrebuild,
+			cfg:  Config{UserName: "user", Password: "pass", RunPublish: "no"},
+			want: []string{
+				"npx expo login -u user -p pass",
+				"npx expo prebuild",
+				"npx expo logout",
+			},
+		},
+		{
+			name: "no login without credentials",
+			cfg:  Config{RunPublish: "no"},
+			want: []string{"expo eject --non-interactive"},
+		},
+		{
+			name:    "logout if eject fails",
+			cfg:     Config{UserName: "user", Password: "pass", RunPublish: "yes"},
+			errors:  map[string]error{"expo eject": errors.New("exit status 1")},
+			want:    []string{"expo login --non-interactive -u user -p pass", "expo eject --non-interactive", "expo logout --non-interactive"},
+			wantErr: "Failed to eject project: exit status 1",
+		},
+		{
+			name:    "logout if publish fails",
+			cfg:     Config{UserName: "user", Password: "pass", RunPublish: "yes"},
+			errors:  map[string]error{"expo publish": errors.New("exit status 1")},
+			want:    []string{"expo login --non-interactive -u user -p pass", "expo eject --non-interactive", "expo publish --non-interactive", "expo logout --non-interactive"},
+			wantErr: "Failed to publish project: exit status 1",
+		},
+		{
+			name:    "no detach if login fails",
+			cfg:     Config{UserName: "user", Password: "pass"},
+			errors:  map[string]error{"expo login": errors.New("exit status 1")},
+			want:    []string{"expo login --non-interactive -u user -p pass"},
+			wantErr: "Failed to log in to your provided Expo account: exit status 1",
+		},
+		{
+			name: "npm install after override",
+			cfg:  Config{OverrideReactNativeVersion: "0.63.4", PackageManager: PackageManagerAuto},
+			want: []string{"expo eject --non-interactive", "npm install"},
+		},
+		{
+			name:  "yarn install after override with yarn.lock",
+			cfg:   Config{OverrideReactNativeVersion: "0.63.4", PackageManager: PackageManagerAuto},
+			files: map[string]string{"yarn.lock": ""},
+			want:  []string{"expo eject --non-interactive", "yarn install"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string]string{}
+			for name, content := range projectFiles {
+				files[name] = content
+			}
+			for name, content := range tt.files {
+				files[name] = content
+			}
+			workdir := createProject(t, files)
+
+			runner := newEjectingCommandRunner(t)
+			for prefix, err := range tt.errors {
+				runner.errors[prefix] = err
+			}
+			cfg := tt.cfg
+			cfg.Workdir = workdir
+
+			expo := newTestExpo(workdir, runner)
+			if tt.mode != "" {
+				expo.Mode = tt.mode
+				expo.CLI = ExpoCLI{Command: []string{"npx", "expo"}}
+			}
+
+			err := authenticatedDetach(context.Background(), expo, cfg, &cleanupStack{})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Fatalf("error = %v, want %s", err, tt.wantErr)
+			}
+			if got := runner.lines(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("commands =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestAuthenticatedDetach_AccessToken(t *testing.T) {
+	workdir := createProject(t, projectFiles)
+	runner := newEjectingCommandRunner(t)
+	expo := newTestExpo(workdir, runner)
+	expo.Token = "token"
+
+	if err := authenticatedDetach(context.Background(), expo, Config{Workdir: workdir, AccessToken: "token", RunPublish: "yes"}, &cleanupStack{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := []string{"expo eject --non-interactive", "expo publish --non-interactive"}
+	if got := runner.lines(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("commands = %v, want %v", got, want)
+	}
+	for _, cmd := range runner.commands {
+		if !reflect.DeepEqual(cmd.Envs, []string{"EXPO_TOKEN=token"}) {
+			t.Errorf("%s envs = %v, want EXPO_TOKEN", cmd, cmd.Envs)
+		}
+		if cmd.Dir != workdir {
+			t.Errorf("%s dir = %s, want %s", cmd, cmd.Dir, workdir)
+		}
+	}
+}
+
+func TestDetach_OverrideModifiesPackageJSON(t *testing.T) {
+	workdir := createProject(t, projectFiles)
+	runner := newEjectingCommandRunner(t)
+
+	cfg := Config{Workdir: workdir, OverrideReactNativeVersion: "0.63.4", PackageManager: PackageManagerAuto}
+	if err := detach(context.Background(), newTestExpo(workdir, runner), cfg); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	got, err := fileutil.ReadStringFromFile(filepath.Join(workdir, "package.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Replace(projectFiles["package.json"], `"react-native": "0.63.2"`, `"react-native": "0.63.4"`, 1)
+	if got != want {
+		t.Errorf("package.json =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDetach_PrebuildPlatform(t *testing.T) {
+	workdir := createProject(t, map[string]string{
+		"package.json": `{"dependencies": {"expo": "^49.0.0"}}`,
+		"app.json":     `{"expo": {"name": "app"}}`,
+	})
+	runner := newEjectingCommandRunner(t)
+	expo := newTestExpo(workdir, runner)
+	expo.Mode = EjectModePrebuild
+	expo.CLI = ExpoCLI{Command: []string{"npx", "expo"}}
+
+	if err := detach(context.Background(), expo, Config{Workdir: workdir, Platforms: PlatformIOS}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := []string{"npx expo prebuild --platform ios"}
+	if got := runner.lines(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("commands = %v, want %v", got, want)
+	}
+}
+
+func TestDetach_ExistingNativeDirs(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy ExistingNativeDirsStrategy
+		files    map[string]string
+		want     []string
+		wantErr  string
+	}{
+		{
+			name:     "skip",
+			strategy: ExistingNativeDirsSkip,
+			files:    nativeProjectFiles,
+		},
+		{
+			name:     "clean",
+			strategy: ExistingNativeDirsClean,
+			files:    nativeProjectFiles,
+			want:     []string{"expo eject --non-interactive"},
+		},
+		{
+			name:     "fail",
+			strategy: ExistingNativeDirsFail,
+			files:    nativeProjectFiles,
+			wantErr:  "existing_native_dirs is fail: the project is already ejected (ios directory exists, android directory exists)",
+		},
+		{
+			name:     "managed project with the Expo config at the root of app.json",
+			strategy: ExistingNativeDirsFail,
+			files:    map[string]string{"app.json": `{"name": "app", "slug": "app", "sdkVersion": "39.0.0"}`},
+			want:     []string{"expo eject --non-interactive"},
+		},
+		{
+			name:     "clean bare workflow project",
+			strategy: ExistingNativeDirsClean,
+			files:    map[string]string{"app.json": `{"name": "app", "displayName": "App"}`},
+			wantErr:  "existing_native_dirs is clean, but a bare workflow project can not be regenerated: app.json has no expo config",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workdir := createProject(t, projectFiles)
+			writeFiles(t, workdir, tt.files)
+			runner := newEjectingCommandRunner(t)
+
+			err := detach(context.Background(), newTestExpo(workdir, runner), Config{Workdir: workdir, ExistingNativeDirs: tt.strategy})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Fatalf("error = %v, want %s", err, tt.wantErr)
+			}
+			if got := runner.lines(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("commands = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetach_CleanRestoresNativeDirsOnFailure(t *testing.T) {
+	workdir := createProject(t, projectFiles)
+	writeFiles(t, workdir, map[string]string{"ios/original": "original"})
+	runner := newFakeCommandRunner()
+	runner.hooks["expo eject"] = func(cmd Command) {
+		writeFiles(t, cmd.Dir, map[string]string{"ios/partial": ""})
+	}
+	runner.errors["expo eject"] = errors.New("exit status 1")
+
+	err := detach(context.Background(), newTestExpo(workdir, runner), Config{Workdir: workdir, ExistingNativeDirs: ExistingNativeDirsClean})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if got, err := fileutil.ReadStringFromFile(filepath.Join(workdir, "ios", "original")); err != nil || got != "original" {
+		t.Errorf("ios/original = %q (%v), want restored", got, err)
+	}
+	if exist, err := pathutil.IsPathExists(filepath.Join(workdir, "ios", "partial")); err != nil || exist {
+		t.Errorf("ios/partial exists = %v (%v), want removed", exist, err)
+	}
+	assertNoNativeDirsBackup(t, workdir)
+}
+
+func TestDetach_CleanRemovesBackup(t *testing.T) {
+	workdir := createProject(t, projectFiles)
+	writeFiles(t, workdir, nativeProjectFiles)
+
+	err := detach(context.Background(), newTestExpo(workdir, newEjectingCommandRunner(t)), Config{Workdir: workdir, ExistingNativeDirs: ExistingNativeDirsClean})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assertNoNativeDirsBackup(t, workdir)
+}
+
+func assertNoNativeDirsBackup(t *testing.T, workdir string) {
+	backups, err := filepath.Glob(filepath.Join(workdir, ".native-dirs-backup*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) > 0 {
+		t.Errorf("backups = %v, want removed", backups)
+	}
+}
+
+func TestDetach_KilledDependencyInstallNotDiagnosed(t *testing.T) {
+	workdir := createProject(t, projectFiles)
+	runner := newEjectingCommandRunner(t)
+	killed := &CommandKilledError{Cmd: NewCommand("npm", "install"), Err: context.DeadlineExceeded}
+	runner.errors["npm install"] = killed
+	// The incomplete output matches a known failure, it must not be reported as the cause.
+	runner.outputs["npm install"] = "npm ERR! code E404"
+
+	cfg := Config{Workdir: workdir, OverrideReactNativeVersion: "0.63.4", PackageManager: PackageManagerNpm}
+	err := detach(context.Background(), newTestExpo(workdir, runner), cfg)
+	if err != killed {
+		t.Errorf("detach() error = %v, want %v", err, killed)
+	}
+}
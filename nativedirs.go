@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-tools/xcode-project/serialized"
+)
+
+// ExistingNativeDirsStrategy selects what to do if the project already has native directories.
+type ExistingNativeDirsStrategy string
+
+const (
+	// ExistingNativeDirsSkip skips the eject and uses the existing native projects.
+	ExistingNativeDirsSkip ExistingNativeDirsStrategy = "skip"
+	// ExistingNativeDirsClean backs up the existing native directories and regenerates them.
+	ExistingNativeDirsClean ExistingNativeDirsStrategy = "clean"
+	// ExistingNativeDirsFail fails the Step.
+	ExistingNativeDirsFail ExistingNativeDirsStrategy = "fail"
+)
+
+// existingNativeProject describes an already ejected project.
+type existingNativeProject struct {
+	// Dirs are the existing native directories of the selected platforms.
+	Dirs []string
+	// Bare is set if app.json has no Expo config at all, like the app.json of a bare workflow project.
+	Bare bool
+}
+
+func (p existingNativeProject) found() bool {
+	return len(p.Dirs) > 0 || p.Bare
+}
+
+// String ...
+func (p existingNativeProject) String() string {
+	var findings []string
+	for _, dir := range p.Dirs {
+		findings = append(findings, fmt.Sprintf("%s directory exists", filepath.Base(dir)))
+	}
+	if p.Bare {
+		findings = append(findings, "app.json has no expo config (bare workflow)")
+	}
+	return strings.Join(findings, ", ")
+}
+
+// detectExistingNativeProject looks for the selected platforms' native directories and a bare workflow app.json.
+func detectExistingNativeProject(workdir string, platform Platform) (existingNativeProject, error) {
+	var existing existingNativeProject
+
+	var dirs []string
+	if platform.IOS() {
+		dirs = append(dirs, filepath.Join(workdir, "ios"))
+	}
+	if platform.Android() {
+		dirs = append(dirs, filepath.Join(workdir, "android"))
+	}
+	for _, dir := range dirs {
+		if exist, err := pathutil.IsDirExists(dir); err != nil {
+			return existingNativeProject{}, err
+		} else if exist {
+			existing.Dirs = append(existing.Dirs, dir)
+		}
+	}
+
+	bare, err := isBareAppJSON(workdir)
+	if err != nil {
+		return existingNativeProject{}, err
+	}
+	existing.Bare = bare
+
+	return existing, nil
+}
+
+// expoConfigRootKeys are app.json keys used only by the Expo config, app.json may hold the Expo config at its root.
+var expoConfigRootKeys = []string{"slug", "sdkVersion", "platforms", "ios", "android", "web", "plugins", "runtimeVersion", "updates"}
+
+// isBareAppJSON reports whether the project has an app.json without any Expo config,
+// like the app.json of a bare React Native project, and no dynamic config.
+func isBareAppJSON(workdir string) (bool, error) {
+	for _, name := range []string{"app.config.js", "app.config.ts"} {
+		if exist, err := pathutil.IsPathExists(filepath.Join(workdir, name)); err != nil {
+			return false, err
+		} else if exist {
+			return false, nil
+		}
+	}
+
+	appJSONPth := filepath.Join(workdir, "app.json")
+	if exist, err := pathutil.IsPathExists(appJSONPth); err != nil {
+		return false, err
+	} else if !exist {
+		return false, nil
+	}
+
+	b, err := fileutil.ReadBytesFromFile(appJSONPth)
+	if err != nil {
+		return false, fmt.Errorf("Failed to read app.json file: %s", err)
+	}
+	var appJSON serialized.Object
+	if err := json.Unmarshal(b, &appJSON); err != nil {
+		return false, fmt.Errorf("Failed to parse app.json file: %s", err)
+	}
+	if _, ok := appJSON["expo"]; ok {
+		return false, nil
+	}
+	for _, key := range expoConfigRootKeys {
+		if _, ok := appJSON[key]; ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// nativeDirsBackup holds the native directories moved aside before regenerating them.
+type nativeDirsBackup struct {
+	dir string
+	// dirs are the original paths of the backed up directories.
+	dirs []string
+}
+
+// backupNativeDirs moves the native directories into a backup directory inside the workdir,
+// as a rename into the system temp directory fails if it is on another filesystem.
+// Nothing is moved in dry run mode, the plan gets a note instead.
+func backupNativeDirs(workdir string, dirs []string, plan *Plan) (nativeDirsBackup, error) {
+	if plan != nil {
+		for _, dir := range dirs {
+			plan.AddNote("move %s into a backup directory, restored if the regeneration fails", dir)
+		}
+		return nativeDirsBackup{}, nil
+	}
+
+	backupDir, err := ioutil.TempDir(workdir, ".native-dirs-backup")
+	if err != nil {
+		return nativeDirsBackup{}, fmt.Errorf("Failed to create the native directories backup: %s", err)
+	}
+
+	backup := nativeDirsBackup{dir: backupDir}
+	for _, dir := range dirs {
+		if err := os.Rename(dir, filepath.Join(backupDir, filepath.Base(dir))); err != nil {
+			if restoreErr := backup.restore(); restoreErr != nil {
+				log.Warnf("Failed to restore the native directories from %s: %s", backupDir, restoreErr)
+			}
+			return nativeDirsBackup{}, fmt.Errorf("Failed to back up %s: %s", dir, err)
+		}
+		backup.dirs = append(backup.dirs, dir)
+		log.Printf("Moved %s to %s", dir, backupDir)
+	}
+	return backup, nil
+}
+
+// restore replaces the regenerated native directories with the backed up ones, and removes the backup.
+func (b nativeDirsBackup) restore() error {
+	for _, dir := range b.dirs {
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+		if err := os.Rename(filepath.Join(b.dir, filepath.Base(dir)), dir); err != nil {
+			return err
+		}
+	}
+	return b.remove()
+}
+
+// remove deletes the backup once the native directories are regenerated.
+func (b nativeDirsBackup) remove() error {
+	if b.dir == "" {
+		return nil
+	}
+	return os.RemoveAll(b.dir)
+}
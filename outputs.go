@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+)
+
+const (
+	iosProjectDirKey      = "EXPO_IOS_PROJECT_DIR"
+	iosWorkspacePathKey   = "EXPO_IOS_WORKSPACE_PATH"
+	iosSchemeKey          = "EXPO_IOS_SCHEME"
+	androidProjectPathKey = "EXPO_ANDROID_PROJECT_PATH"
+	androidModuleKey      = "EXPO_ANDROID_MODULE"
+	androidGradlewPathKey = "EXPO_ANDROID_GRADLEW_PATH"
+)
+
+const (
+	defaultAndroidModule      = "app"
+	xcodeWorkspaceExtension   = ".xcworkspace"
+	xcodeProjectExtension     = ".xcodeproj"
+	xcodeSchemeExtension      = ".xcscheme"
+	androidBuildGradleFile    = "build.gradle"
+	androidBuildGradleKtsFile = "build.gradle.kts"
+)
+
+// NativeProjects holds the locations of the generated native projects.
+// Fields of a platform which was not generated are left empty.
+type NativeProjects struct {
+	IOSProjectDir      string
+	IOSWorkspacePath   string
+	IOSScheme          string
+	AndroidProjectPath string
+	AndroidModule      string
+	AndroidGradlewPath string
+}
+
+// findNativeProjects discovers the selected platforms' native projects in the ejected project's ios and android directories.
+// The scheme named after the app is preferred.
+func findNativeProjects(workdir string, platform Platform, config ExpoConfig) (NativeProjects, error) {
+	var projects NativeProjects
+
+	if platform.IOS() {
+		iosDir := filepath.Join(workdir, "ios")
+		if exist, err := pathutil.IsDirExists(iosDir); err != nil {
+			return NativeProjects{}, err
+		} else if !exist {
+			return NativeProjects{}, fmt.Errorf("ios directory not found in %s", workdir)
+		}
+
+		projects.IOSProjectDir = iosDir
+		if err := findIOSProject(iosDir, config.Name, &projects); err != nil {
+			return NativeProjects{}, err
+		}
+	}
+
+	if platform.Android() {
+		androidDir := filepath.Join(workdir, "android")
+		if exist, err := pathutil.IsDirExists(androidDir); err != nil {
+			return NativeProjects{}, err
+		} else if !exist {
+			return NativeProjects{}, fmt.Errorf("android directory not found in %s", workdir)
+		}
+
+		projects.AndroidProjectPath = androidDir
+		if err := findAndroidProject(androidDir, &projects); err != nil {
+			return NativeProjects{}, err
+		}
+	}
+
+	return projects, nil
+}
+
+func findIOSProject(iosDir, appName string, projects *NativeProjects) error {
+	xcodeProjects, err := filepath.Glob(filepath.Join(iosDir, "*"+xcodeProjectExtension))
+	if err != nil {
+		return err
+	}
+	if len(xcodeProjects) == 0 {
+		return fmt.Errorf("no Xcode project found in %s", iosDir)
+	}
+	sort.Strings(xcodeProjects)
+	xcodeProject := xcodeProjects[0]
+	projectName := strings.TrimSuffix(filepath.Base(xcodeProject), xcodeProjectExtension)
+
+	workspaces, err := filepath.Glob(filepath.Join(iosDir, "*"+xcodeWorkspaceExtension))
+	if err != nil {
+		return err
+	}
+	sort.Strings(workspaces)
+
+	podfileExist, err := pathutil.IsPathExists(filepath.Join(iosDir, "Podfile"))
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(workspaces) > 0:
+		projects.IOSWorkspacePath = workspaces[0]
+	case podfileExist:
+		// The workspace is created by `pod install`, which is run by a later Step.
+		projects.IOSWorkspacePath = filepath.Join(iosDir, projectName+xcodeWorkspaceExtension)
+	default:
+		projects.IOSWorkspacePath = xcodeProject
+	}
+
+	schemes, err := filepath.Glob(filepath.Join(xcodeProject, "xcshareddata", "xcschemes", "*"+xcodeSchemeExtension))
+	if err != nil {
+		return err
+	}
+	sort.Strings(schemes)
+	for _, preferred := range []string{appName, projectName} {
+		for _, scheme := range schemes {
+			name := strings.TrimSuffix(filepath.Base(scheme), xcodeSchemeExtension)
+			if preferred != "" && name == preferred {
+				projects.IOSScheme = name
+				return nil
+			}
+		}
+	}
+	if len(schemes) > 0 {
+		projects.IOSScheme = strings.TrimSuffix(filepath.Base(schemes[0]), xcodeSchemeExtension)
+		return nil
+	}
+
+	log.Warnf("No shared Xcode scheme found in %s, using the project name as scheme", xcodeProject)
+	projects.IOSScheme = projectName
+	return nil
+}
+
+func findAndroidProject(androidDir string, projects *NativeProjects) error {
+	gradlew := filepath.Join(androidDir, "gradlew")
+	if exist, err := pathutil.IsPathExists(gradlew); err != nil {
+		return err
+	} else if exist {
+		projects.AndroidGradlewPath = gradlew
+	}
+
+	for _, buildFile := range []string{androidBuildGradleFile, androidBuildGradleKtsFile} {
+		if exist, err := pathutil.IsPathExists(filepath.Join(androidDir, defaultAndroidModule, buildFile)); err != nil {
+			return err
+		} else if exist {
+			projects.AndroidModule = defaultAndroidModule
+			return nil
+		}
+	}
+
+	return fmt.Errorf("no %s module found in %s", defaultAndroidModule, androidDir)
+}
+
+// exportNativeProjects exports the discovered native project locations as step outputs.
+func exportNativeProjects(projects NativeProjects) error {
+	return exportOutputs(
+		output{iosProjectDirKey, projects.IOSProjectDir},
+		output{iosWorkspacePathKey, projects.IOSWorkspacePath},
+		output{iosSchemeKey, projects.IOSScheme},
+		output{androidProjectPathKey, projects.AndroidProjectPath},
+		output{androidModuleKey, projects.AndroidModule},
+		output{androidGradlewPathKey, projects.AndroidGradlewPath},
+	)
+}
+
+// output is a step output, it is not exported if its value is empty.
+type output struct {
+	key   string
+	value string
+}
+
+// exportOutputs exports the outputs with envman and prints them.
+func exportOutputs(outputs ...output) error {
+	for _, output := range outputs {
+		if output.value == "" {
+			continue
+		}
+		if err := exportEnvironmentWithEnvman(output.key, output.value); err != nil {
+			return fmt.Errorf("Failed to export %s: %s", output.key, err)
+		}
+		log.Printf("%s: %s", output.key, output.value)
+	}
+	return nil
+}
+
+func exportEnvironmentWithEnvman(key, value string) error {
+	cmd := command.New("envman", "add", "--key", key)
+	cmd.SetStdin(strings.NewReader(value))
+	return cmd.Run()
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFindNativeProjects(t *testing.T) {
+	const scheme = "ios/MyApp.xcodeproj/xcshareddata/xcschemes/"
+
+	tests := []struct {
+		name     string
+		files    map[string]string
+		platform Platform
+		appName  string
+		// want holds the paths relative to the project.
+		want    NativeProjects
+		wantErr bool
+	}{
+		{
+			name: "workspace and app-name scheme",
+			files: map[string]string{
+				"ios/MyApp.xcodeproj/project.pbxproj":            "",
+				"ios/MyApp.xcworkspace/contents.xcworkspacedata": "",
+				scheme + "MyApp.xcscheme":                        "",
+				scheme + "My App.xcscheme":                       "",
+				"android/gradlew":                                "",
+				"android/app/build.gradle":                       "",
+			},
+			platform: PlatformAll,
+			appName:  "My App",
+			want: NativeProjects{
+				IOSProjectDir:      "ios",
+				IOSWorkspacePath:   "ios/MyApp.xcworkspace",
+				IOSScheme:          "My App",
+				AndroidProjectPath: "android",
+				AndroidModule:      "app",
+				AndroidGradlewPath: "android/gradlew",
+			},
+		},
+		{
+			name: "project-name scheme without an app-name scheme",
+			files: map[string]string{
+				"ios/MyApp.xcodeproj/project.pbxproj": "",
+				scheme + "AMyAppTests.xcscheme":       "",
+				scheme + "MyApp.xcscheme":             "",
+			},
+			platform: PlatformIOS,
+			appName:  "Other",
+			want:     NativeProjects{IOSProjectDir: "ios", IOSWorkspacePath: "ios/MyApp.xcodeproj", IOSScheme: "MyApp"},
+		},
+		{
+			name: "expected workspace with a Podfile",
+			files: map[string]string{
+				"ios/MyApp.xcodeproj/project.pbxproj": "",
+				"ios/Podfile":                         "",
+			},
+			platform: PlatformIOS,
+			want:     NativeProjects{IOSProjectDir: "ios", IOSWorkspacePath: "ios/MyApp.xcworkspace", IOSScheme: "MyApp"},
+		},
+		{
+			name:     "xcodeproj without workspace and Podfile",
+			files:    map[string]string{"ios/MyApp.xcodeproj/project.pbxproj": ""},
+			platform: PlatformIOS,
+			want:     NativeProjects{IOSProjectDir: "ios", IOSWorkspacePath: "ios/MyApp.xcodeproj", IOSScheme: "MyApp"},
+		},
+		{
+			name:     "build.gradle.kts",
+			files:    map[string]string{"android/app/build.gradle.kts": ""},
+			platform: PlatformAndroid,
+			want:     NativeProjects{AndroidProjectPath: "android", AndroidModule: "app"},
+		},
+		{
+			name:     "android only ignores the missing ios directory",
+			files:    map[string]string{"android/app/build.gradle": ""},
+			platform: PlatformAndroid,
+			want:     NativeProjects{AndroidProjectPath: "android", AndroidModule: "app"},
+		},
+		{
+			name:     "ios only ignores the missing android directory",
+			files:    map[string]string{"ios/MyApp.xcodeproj/project.pbxproj": ""},
+			platform: PlatformIOS,
+			want:     NativeProjects{IOSProjectDir: "ios", IOSWorkspacePath: "ios/MyApp.xcodeproj", IOSScheme: "MyApp"},
+		},
+		{
+			name:     "missing android directory",
+			files:    map[string]string{"ios/MyApp.xcodeproj/project.pbxproj": ""},
+			platform: PlatformAll,
+			wantErr:  true,
+		},
+		{
+			name:     "no Xcode project",
+			files:    map[string]string{"ios/Podfile": ""},
+			platform: PlatformIOS,
+			wantErr:  true,
+		},
+		{
+			name:     "no android module",
+			files:    map[string]string{"android/build.gradle": ""},
+			platform: PlatformAndroid,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workdir := createProject(t, tt.files)
+
+			got, err := findNativeProjects(workdir, tt.platform, ExpoConfig{Name: tt.appName})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("findNativeProjects() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			abs := func(pth string) string {
+				if pth == "" {
+					return ""
+				}
+				return filepath.Join(workdir, pth)
+			}
+			want := tt.want
+			want.IOSProjectDir = abs(want.IOSProjectDir)
+			want.IOSWorkspacePath = abs(want.IOSWorkspacePath)
+			want.AndroidProjectPath = abs(want.AndroidProjectPath)
+			want.AndroidGradlewPath = abs(want.AndroidGradlewPath)
+			if got != want {
+				t.Errorf("findNativeProjects() =\n%+v\nwant\n%+v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+const (
+	dependenciesSection    = "dependencies"
+	devDependenciesSection = "devDependencies"
+)
+
+// dependencyOverride sets or removes a package in one of the package.json dependency sections.
+type dependencyOverride struct {
+	Section string
+	Name    string
+	Version string
+	Remove  bool
+}
+
+// String ...
+func (o dependencyOverride) String() string {
+	if o.Remove {
+		return fmt.Sprintf("remove %s from %s", o.Name, o.Section)
+	}
+	return fmt.Sprintf("set %s@%s in %s", o.Name, o.Version, o.Section)
+}
+
+// parseDependencyOverride parses a single override line:
+// [dependencies:|devDependencies:]name@version to set a package,
+// ![dependencies:|devDependencies:]name to remove it.
+func parseDependencyOverride(line string) (dependencyOverride, error) {
+	override := dependencyOverride{Section: dependenciesSection}
+
+	spec := line
+	if strings.HasPrefix(spec, "!") {
+		override.Remove = true
+		spec = strings.TrimSpace(strings.TrimPrefix(spec, "!"))
+	}
+
+	for _, section := range []string{dependenciesSection, devDependenciesSection} {
+		if strings.HasPrefix(spec, section+":") {
+			override.Section = section
+			spec = strings.TrimPrefix(spec, section+":")
+			break
+		}
+	}
+
+	if override.Remove {
+		override.Name = spec
+	} else {
+		// Scoped package names start with @, the version separator is the next @.
+		idx := strings.Index(strings.TrimPrefix(spec, "@"), "@")
+		if idx == -1 {
+			return dependencyOverride{}, fmt.Errorf("%s: missing version, expected name@version", line)
+		}
+		if strings.HasPrefix(spec, "@") {
+			idx++
+		}
+		override.Name, override.Version = spec[:idx], spec[idx+1:]
+		if override.Version == "" {
+			return dependencyOverride{}, fmt.Errorf("%s: empty version", line)
+		}
+	}
+
+	if override.Name == "" || override.Name == "@" {
+		return dependencyOverride{}, fmt.Errorf("%s: missing package name", line)
+	}
+	return override, nil
+}
+
+// dependencyOverrides returns the overrides requested by the inputs: the versions matching the Expo SDK
+// if auto_align_versions is set, then override_react_native_version, then dependency_overrides,
+// so the more specific inputs win.
+func dependencyOverrides(cfg Config, sdkVersion int) ([]dependencyOverride, error) {
+	var overrides []dependencyOverride
+	if cfg.AutoAlignVersions == "yes" {
+		aligned, err := alignedDependencyOverrides(sdkVersion)
+		if err != nil {
+			return nil, fmt.Errorf("auto_align_versions is enabled: %s", err)
+		}
+		overrides = append(overrides, aligned...)
+	}
+
+	if cfg.OverrideReactNativeVersion != "" {
+		overrides = append(overrides, dependencyOverride{
+			Section: dependenciesSection,
+			Name:    "react-native",
+			Version: cfg.OverrideReactNativeVersion,
+		})
+	}
+
+	var errs []string
+	for _, line := range strings.Split(cfg.DependencyOverrides, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		override, err := parseDependencyOverride(line)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		overrides = append(overrides, override)
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid dependency overrides:\n- %s", strings.Join(errs, "\n- "))
+	}
+	return overrides, nil
+}
+
+// applyDependencyOverrides applies the overrides to the package.json document in order.
+func applyDependencyOverrides(packages *jsonDocument, overrides []dependencyOverride) error {
+	for _, override := range overrides {
+		log.Printf("%s", override)
+
+		if override.Remove {
+			if err := packages.Delete(override.Section, override.Name); err == errJSONKeyNotFound {
+				log.Warnf("%s is not in %s, nothing to remove", override.Name, override.Section)
+			} else if err != nil {
+				return fmt.Errorf("Failed to remove %s from %s in package.json file: %s", override.Name, override.Section, err)
+			}
+			continue
+		}
+
+		if err := packages.Set(override.Version, override.Section, override.Name); err != nil {
+			return fmt.Errorf("Failed to set %s in %s in package.json file: %s", override.Name, override.Section, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDependencyOverride(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    dependencyOverride
+		wantErr bool
+	}{
+		{
+			line: "react-native@0.63.4",
+			want: dependencyOverride{Section: dependenciesSection, Name: "react-native", Version: "0.63.4"},
+		},
+		{
+			line: "@scope/pkg@^1",
+			want: dependencyOverride{Section: dependenciesSection, Name: "@scope/pkg", Version: "^1"},
+		},
+		{
+			line: "react-native@https://github.com/expo/react-native/archive/sdk-39.0.0.tar.gz",
+			want: dependencyOverride{Section: dependenciesSection, Name: "react-native", Version: "https://github.com/expo/react-native/archive/sdk-39.0.0.tar.gz"},
+		},
+		{
+			line: "devDependencies:@babel/core@~7.9.0",
+			want: dependencyOverride{Section: devDependenciesSection, Name: "@babel/core", Version: "~7.9.0"},
+		},
+		{
+			line: "dependencies:expo-updates@~0.3.2",
+			want: dependencyOverride{Section: dependenciesSection, Name: "expo-updates", Version: "~0.3.2"},
+		},
+		{
+			line: "!expo-dev-client",
+			want: dependencyOverride{Section: dependenciesSection, Name: "expo-dev-client", Remove: true},
+		},
+		{
+			line: "! devDependencies:@types/react",
+			want: dependencyOverride{Section: devDependenciesSection, Name: "@types/react", Remove: true},
+		},
+		{line: "react-native", wantErr: true},
+		{line: "@scope/pkg", wantErr: true},
+		{line: "react-native@", wantErr: true},
+		{line: "@0.63.4", wantErr: true},
+		{line: "devDependencies:", wantErr: true},
+		{line: "!", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, err := parseDependencyOverride(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDependencyOverride() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseDependencyOverride() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDependencyOverrides_Order(t *testing.T) {
+	cfg := Config{
+		AutoAlignVersions:          "yes",
+		OverrideReactNativeVersion: "0.63.3",
+		DependencyOverrides:        "# pinned\nreact-native@0.63.4\n\n!expo-dev-client\n",
+	}
+
+	got, err := dependencyOverrides(cfg, 39)
+	if err != nil {
+		t.Fatalf("dependencyOverrides() error = %v", err)
+	}
+	want := []dependencyOverride{
+		{Section: dependenciesSection, Name: "react-native", Version: "0.63.2"},
+		{Section: dependenciesSection, Name: "react", Version: "16.13.1"},
+		{Section: dependenciesSection, Name: "react-native", Version: "0.63.3"},
+		{Section: dependenciesSection, Name: "react-native", Version: "0.63.4"},
+		{Section: dependenciesSection, Name: "expo-dev-client", Remove: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("dependencyOverrides() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	cfg.DependencyOverrides = "react-native\nreact@"
+	if _, err := dependencyOverrides(cfg, 39); err == nil {
+		t.Error("dependencyOverrides() succeeded with invalid lines")
+	}
+}
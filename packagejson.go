@@ -0,0 +1,450 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-tools/xcode-project/serialized"
+)
+
+const defaultJSONIndent = "  "
+
+var errJSONKeyNotFound = errors.New("key not found")
+
+// jsonDocument edits a JSON document in place: only the changed members are rewritten,
+// the order of keys, the indentation, the line endings and the trailing newline are kept.
+type jsonDocument struct {
+	content []byte
+}
+
+// jsonMember is a key-value pair of a JSON object, positions are byte offsets into the document.
+type jsonMember struct {
+	name       string
+	keyStart   int
+	keyEnd     int
+	valueStart int
+	valueEnd   int
+}
+
+// jsonObject is a JSON object, start and end are the offsets of the opening and closing braces.
+type jsonObject struct {
+	start   int
+	end     int
+	members []jsonMember
+}
+
+func (o jsonObject) member(name string) (int, bool) {
+	for i, m := range o.members {
+		if m.name == name {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// sorted reports whether the object's keys are sorted, a single key does not tell the order.
+func (o jsonObject) sorted() bool {
+	return len(o.members) > 1 && sort.SliceIsSorted(o.members, func(i, j int) bool {
+		return o.members[i].name < o.members[j].name
+	})
+}
+
+func newJSONDocument(content []byte) (*jsonDocument, error) {
+	var v interface{}
+	if err := json.Unmarshal(content, &v); err != nil {
+		return nil, err
+	}
+
+	d := &jsonDocument{content: content}
+	if _, err := d.object(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Bytes returns the document's current content.
+func (d *jsonDocument) Bytes() []byte {
+	return d.content
+}
+
+// Object decodes the object at the given key path.
+func (d *jsonDocument) Object(keys ...string) (serialized.Object, error) {
+	var root serialized.Object
+	if err := json.Unmarshal(d.content, &root); err != nil {
+		return nil, err
+	}
+	obj := root
+	for _, key := range keys {
+		child, err := obj.Object(key)
+		if err != nil {
+			return nil, err
+		}
+		obj = child
+	}
+	return obj, nil
+}
+
+// Set sets the value at the given key path, missing parent objects are created.
+// New keys are appended to the end of their object, or kept in order if the object's keys are sorted.
+func (d *jsonDocument) Set(value interface{}, keys ...string) error {
+	if len(keys) == 0 {
+		return errors.New("no key provided")
+	}
+	parentKeys, key := keys[:len(keys)-1], keys[len(keys)-1]
+
+	parent, err := d.object(parentKeys...)
+	if err == errJSONKeyNotFound && len(parentKeys) > 0 {
+		return d.Set(map[string]interface{}{key: value}, parentKeys...)
+	} else if err != nil {
+		return err
+	}
+
+	if i, ok := parent.member(key); ok {
+		m := parent.members[i]
+		encoded, err := d.marshal(value, d.lineIndent(m.keyStart))
+		if err != nil {
+			return err
+		}
+		d.replace(m.valueStart, m.valueEnd, encoded)
+		return nil
+	}
+
+	return d.insert(parent, key, value)
+}
+
+// Delete removes the member at the given key path, together with its separating comma.
+func (d *jsonDocument) Delete(keys ...string) error {
+	if len(keys) == 0 {
+		return errors.New("no key provided")
+	}
+	parentKeys, key := keys[:len(keys)-1], keys[len(keys)-1]
+
+	parent, err := d.object(parentKeys...)
+	if err != nil {
+		return err
+	}
+	i, ok := parent.member(key)
+	if !ok {
+		return errJSONKeyNotFound
+	}
+
+	members := parent.members
+	switch {
+	case len(members) == 1:
+		d.replace(parent.start+1, parent.end, nil)
+	case i < len(members)-1:
+		d.replace(members[i].keyStart, members[i+1].keyStart, nil)
+	default:
+		d.replace(members[i-1].valueEnd, members[i].valueEnd, nil)
+	}
+	return nil
+}
+
+func (d *jsonDocument) insert(parent jsonObject, key string, value interface{}) error {
+	encodedKey, err := d.marshal(key, "")
+	if err != nil {
+		return err
+	}
+
+	if len(parent.members) == 0 {
+		if d.compact() {
+			encoded, err := d.marshal(value, "")
+			if err != nil {
+				return err
+			}
+			d.replace(parent.start+1, parent.end, append(append(encodedKey, ':'), encoded...))
+			return nil
+		}
+
+		indent := d.lineIndent(parent.start)
+		childIndent := indent + d.indentUnit()
+		encoded, err := d.marshal(value, childIndent)
+		if err != nil {
+			return err
+		}
+
+		newline := d.newline()
+		var b bytes.Buffer
+		b.WriteString(newline + childIndent)
+		b.Write(encodedKey)
+		b.WriteString(": ")
+		b.Write(encoded)
+		b.WriteString(newline + indent)
+		d.replace(parent.start+1, parent.end, b.Bytes())
+		return nil
+	}
+
+	// Separators are copied from the object's existing members.
+	reference := parent.members[0]
+	indent := d.lineIndent(reference.keyStart)
+	keySeparator := d.content[reference.keyEnd:reference.valueStart]
+	var memberSeparator string
+	switch {
+	case len(parent.members) > 1:
+		memberSeparator = string(d.content[reference.valueEnd:parent.members[1].keyStart])
+	case d.isLineStart(reference.keyStart):
+		memberSeparator = "," + d.newline() + indent
+	case bytes.HasSuffix(keySeparator, []byte(" ")):
+		memberSeparator = ", "
+	default:
+		memberSeparator = ","
+	}
+
+	encoded, err := d.marshal(value, indent)
+	if err != nil {
+		return err
+	}
+	var member bytes.Buffer
+	member.Write(encodedKey)
+	member.Write(keySeparator)
+	member.Write(encoded)
+
+	if parent.sorted() {
+		for _, m := range parent.members {
+			if m.name > key {
+				d.replace(m.keyStart, m.keyStart, append(member.Bytes(), memberSeparator...))
+				return nil
+			}
+		}
+	}
+
+	last := parent.members[len(parent.members)-1]
+	d.replace(last.valueEnd, last.valueEnd, append([]byte(memberSeparator), member.Bytes()...))
+	return nil
+}
+
+func (d *jsonDocument) marshal(value interface{}, indent string) ([]byte, error) {
+	if d.compact() {
+		var b bytes.Buffer
+		encoder := json.NewEncoder(&b)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(value); err != nil {
+			return nil, err
+		}
+		return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+	}
+
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent(indent, d.indentUnit())
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	encoded := bytes.TrimSuffix(b.Bytes(), []byte("\n"))
+	if newline := d.newline(); newline != "\n" {
+		encoded = bytes.Replace(encoded, []byte("\n"), []byte(newline), -1)
+	}
+	return encoded, nil
+}
+
+func (d *jsonDocument) replace(start, end int, with []byte) {
+	content := make([]byte, 0, len(d.content)-(end-start)+len(with))
+	content = append(content, d.content[:start]...)
+	content = append(content, with...)
+	content = append(content, d.content[end:]...)
+	d.content = content
+}
+
+// compact reports whether the document is written in a single line.
+func (d *jsonDocument) compact() bool {
+	return !bytes.Contains(bytes.TrimSpace(d.content), []byte("\n"))
+}
+
+func (d *jsonDocument) newline() string {
+	if bytes.Contains(d.content, []byte("\r\n")) {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// indentUnit returns the indentation of the first indented line.
+func (d *jsonDocument) indentUnit() string {
+	for _, line := range strings.Split(string(d.content), "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed != "" && len(trimmed) < len(line) {
+			return line[:len(line)-len(trimmed)]
+		}
+	}
+	return defaultJSONIndent
+}
+
+// lineIndent returns the leading whitespace of the line containing pos.
+func (d *jsonDocument) lineIndent(pos int) string {
+	lineStart := bytes.LastIndexByte(d.content[:pos], '\n') + 1
+	line := d.content[lineStart:]
+	return string(line[:len(line)-len(bytes.TrimLeft(line, " \t"))])
+}
+
+// isLineStart reports whether only whitespace precedes pos in its line.
+func (d *jsonDocument) isLineStart(pos int) bool {
+	lineStart := bytes.LastIndexByte(d.content[:pos], '\n') + 1
+	return len(bytes.TrimLeft(d.content[lineStart:pos], " \t\r")) == 0
+}
+
+// object parses the object at the given key path.
+func (d *jsonDocument) object(keys ...string) (jsonObject, error) {
+	obj, err := scanJSONObject(d.content, skipJSONWhitespace(d.content, 0))
+	if err != nil {
+		return jsonObject{}, err
+	}
+	for _, key := range keys {
+		i, ok := obj.member(key)
+		if !ok {
+			return jsonObject{}, errJSONKeyNotFound
+		}
+		if obj, err = scanJSONObject(d.content, obj.members[i].valueStart); err != nil {
+			return jsonObject{}, fmt.Errorf("%s: %s", key, err)
+		}
+	}
+	return obj, nil
+}
+
+func skipJSONWhitespace(content []byte, pos int) int {
+	for pos < len(content) && strings.IndexByte(" \t\r\n", content[pos]) != -1 {
+		pos++
+	}
+	return pos
+}
+
+func scanJSONObject(content []byte, pos int) (jsonObject, error) {
+	if pos >= len(content) || content[pos] != '{' {
+		return jsonObject{}, fmt.Errorf("expected object at offset %d", pos)
+	}
+	obj := jsonObject{start: pos}
+
+	pos = skipJSONWhitespace(content, pos+1)
+	if pos < len(content) && content[pos] == '}' {
+		obj.end = pos
+		return obj, nil
+	}
+
+	for {
+		keyStart := pos
+		keyEnd, err := scanJSONString(content, keyStart)
+		if err != nil {
+			return jsonObject{}, err
+		}
+		var name string
+		if err := json.Unmarshal(content[keyStart:keyEnd], &name); err != nil {
+			return jsonObject{}, err
+		}
+
+		pos = skipJSONWhitespace(content, keyEnd)
+		if pos >= len(content) || content[pos] != ':' {
+			return jsonObject{}, fmt.Errorf("expected colon at offset %d", pos)
+		}
+
+		valueStart := skipJSONWhitespace(content, pos+1)
+		valueEnd, err := scanJSONValue(content, valueStart)
+		if err != nil {
+			return jsonObject{}, err
+		}
+		obj.members = append(obj.members, jsonMember{name: name, keyStart: keyStart, keyEnd: keyEnd, valueStart: valueStart, valueEnd: valueEnd})
+
+		pos = skipJSONWhitespace(content, valueEnd)
+		if pos >= len(content) {
+			return jsonObject{}, errors.New("unexpected end of JSON input")
+		}
+		switch content[pos] {
+		case ',':
+			pos = skipJSONWhitespace(content, pos+1)
+		case '}':
+			obj.end = pos
+			return obj, nil
+		default:
+			return jsonObject{}, fmt.Errorf("unexpected character %q at offset %d", content[pos], pos)
+		}
+	}
+}
+
+// scanJSONValue returns the offset right after the value starting at pos.
+func scanJSONValue(content []byte, pos int) (int, error) {
+	if pos >= len(content) {
+		return 0, errors.New("unexpected end of JSON input")
+	}
+
+	switch content[pos] {
+	case '{':
+		obj, err := scanJSONObject(content, pos)
+		if err != nil {
+			return 0, err
+		}
+		return obj.end + 1, nil
+	case '[':
+		pos = skipJSONWhitespace(content, pos+1)
+		if pos < len(content) && content[pos] == ']' {
+			return pos + 1, nil
+		}
+		for {
+			end, err := scanJSONValue(content, pos)
+			if err != nil {
+				return 0, err
+			}
+			pos = skipJSONWhitespace(content, end)
+			if pos >= len(content) {
+				return 0, errors.New("unexpected end of JSON input")
+			}
+			switch content[pos] {
+			case ',':
+				pos = skipJSONWhitespace(content, pos+1)
+			case ']':
+				return pos + 1, nil
+			default:
+				return 0, fmt.Errorf("unexpected character %q at offset %d", content[pos], pos)
+			}
+		}
+	case '"':
+		return scanJSONString(content, pos)
+	default:
+		end := pos
+		for end < len(content) && strings.IndexByte(",}] \t\r\n", content[end]) == -1 {
+			end++
+		}
+		if end == pos {
+			return 0, fmt.Errorf("unexpected character %q at offset %d", content[pos], pos)
+		}
+		return end, nil
+	}
+}
+
+// scanJSONString returns the offset right after the closing quote of the string starting at pos.
+func scanJSONString(content []byte, pos int) (int, error) {
+	if pos >= len(content) || content[pos] != '"' {
+		return 0, fmt.Errorf("expected string at offset %d", pos)
+	}
+	for i := pos + 1; i < len(content); i++ {
+		switch content[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1, nil
+		}
+	}
+	return 0, errors.New("unterminated string")
+}
+
+func parsePackageJSON(pth string) (*jsonDocument, error) {
+	b, err := fileutil.ReadBytesFromFile(pth)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read package.json file: %s", err)
+	}
+
+	packages, err := newJSONDocument(b)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse package.json file: %s", err)
+	}
+	return packages, nil
+}
+
+func savePackageJSON(packages *jsonDocument, pth string) error {
+	if err := fileutil.WriteBytesToFile(pth, packages.Bytes()); err != nil {
+		return fmt.Errorf("Failed to write modified package.json file: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestJSONDocument_Set(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		keys    []string
+		value   interface{}
+		want    string
+	}{
+		{
+			name:    "replace value",
+			content: "{\n  \"name\": \"app\",\n  \"version\": \"1.0.0\"\n}\n",
+			keys:    []string{"version"},
+			value:   "2.0.0",
+			want:    "{\n  \"name\": \"app\",\n  \"version\": \"2.0.0\"\n}\n",
+		},
+		{
+			name:    "append to unsorted object",
+			content: "{\n  \"name\": \"app\",\n  \"dependencies\": {}\n}\n",
+			keys:    []string{"version"},
+			value:   "1.0.0",
+			want:    "{\n  \"name\": \"app\",\n  \"dependencies\": {},\n  \"version\": \"1.0.0\"\n}\n",
+		},
+		{
+			name:    "insert into sorted object",
+			content: "{\n  \"dependencies\": {\n    \"expo\": \"~39.0.2\",\n    \"react-native\": \"0.63.2\"\n  }\n}\n",
+			keys:    []string{"dependencies", "react"},
+			value:   "16.13.1",
+			want:    "{\n  \"dependencies\": {\n    \"expo\": \"~39.0.2\",\n    \"react\": \"16.13.1\",\n    \"react-native\": \"0.63.2\"\n  }\n}\n",
+		},
+		{
+			name:    "append to single member object",
+			content: "{\n  \"name\": \"app\"\n}\n",
+			keys:    []string{"devDependencies", "jest"},
+			value:   "26.0.0",
+			want:    "{\n  \"name\": \"app\",\n  \"devDependencies\": {\n    \"jest\": \"26.0.0\"\n  }\n}\n",
+		},
+		{
+			name:    "create nested parents",
+			content: "{\n  \"name\": \"app\",\n  \"version\": \"1.0.0\",\n  \"private\": true\n}\n",
+			keys:    []string{"expo", "install", "exclude"},
+			value:   []string{"react-native"},
+			want:    "{\n  \"name\": \"app\",\n  \"version\": \"1.0.0\",\n  \"private\": true,\n  \"expo\": {\n    \"install\": {\n      \"exclude\": [\n        \"react-native\"\n      ]\n    }\n  }\n}\n",
+		},
+		{
+			name:    "empty object",
+			content: "{\n  \"dependencies\": {}\n}\n",
+			keys:    []string{"dependencies", "expo"},
+			value:   "~39.0.2",
+			want:    "{\n  \"dependencies\": {\n    \"expo\": \"~39.0.2\"\n  }\n}\n",
+		},
+		{
+			name:    "empty document",
+			content: "{}",
+			keys:    []string{"name"},
+			value:   "app",
+			want:    "{\"name\":\"app\"}",
+		},
+		{
+			name:    "compact",
+			content: `{"a":"1","b":"3"}`,
+			keys:    []string{"c"},
+			value:   "2",
+			want:    `{"a":"1","b":"3","c":"2"}`,
+		},
+		{
+			name:    "compact with spaces",
+			content: `{"name": "app"}`,
+			keys:    []string{"dependencies"},
+			value:   map[string]string{"expo": "~39.0.2"},
+			want:    `{"name": "app", "dependencies": {"expo":"~39.0.2"}}`,
+		},
+		{
+			name:    "tabs",
+			content: "{\n\t\"name\": \"app\"\n}\n",
+			keys:    []string{"dependencies", "expo"},
+			value:   "~39.0.2",
+			want:    "{\n\t\"name\": \"app\",\n\t\"dependencies\": {\n\t\t\"expo\": \"~39.0.2\"\n\t}\n}\n",
+		},
+		{
+			name:    "CRLF",
+			content: "{\r\n  \"name\": \"app\"\r\n}\r\n",
+			keys:    []string{"dependencies", "expo"},
+			value:   "~39.0.2",
+			want:    "{\r\n  \"name\": \"app\",\r\n  \"dependencies\": {\r\n    \"expo\": \"~39.0.2\"\r\n  }\r\n}\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := newJSONDocument([]byte(tt.content))
+			if err != nil {
+				t.Fatalf("newJSONDocument() error = %v", err)
+			}
+			if err := d.Set(tt.value, tt.keys...); err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			if got := string(d.Bytes()); got != tt.want {
+				t.Errorf("Set() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONDocument_Delete(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		keys    []string
+		want    string
+		wantErr error
+	}{
+		{
+			name:    "first member",
+			content: "{\n  \"a\": 1,\n  \"b\": 2,\n  \"c\": 3\n}\n",
+			keys:    []string{"a"},
+			want:    "{\n  \"b\": 2,\n  \"c\": 3\n}\n",
+		},
+		{
+			name:    "middle member",
+			content: "{\n  \"a\": 1,\n  \"b\": 2,\n  \"c\": 3\n}\n",
+			keys:    []string{"b"},
+			want:    "{\n  \"a\": 1,\n  \"c\": 3\n}\n",
+		},
+		{
+			name:    "last member",
+			content: "{\n  \"a\": 1,\n  \"b\": 2,\n  \"c\": 3\n}\n",
+			keys:    []string{"c"},
+			want:    "{\n  \"a\": 1,\n  \"b\": 2\n}\n",
+		},
+		{
+			name:    "only member",
+			content: "{\n  \"resolutions\": {\n    \"react-native\": \"0.63.4\"\n  }\n}\n",
+			keys:    []string{"resolutions", "react-native"},
+			want:    "{\n  \"resolutions\": {}\n}\n",
+		},
+		{
+			name:    "compact",
+			content: `{"a":1,"b":{"c":[1,2]},"d":"}"}`,
+			keys:    []string{"b"},
+			want:    `{"a":1,"d":"}"}`,
+		},
+		{
+			name:    "CRLF",
+			content: "{\r\n  \"a\": 1,\r\n  \"b\": 2\r\n}\r\n",
+			keys:    []string{"b"},
+			want:    "{\r\n  \"a\": 1\r\n}\r\n",
+		},
+		{
+			name:    "missing key",
+			content: `{"a":1}`,
+			keys:    []string{"b"},
+			want:    `{"a":1}`,
+			wantErr: errJSONKeyNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := newJSONDocument([]byte(tt.content))
+			if err != nil {
+				t.Fatalf("newJSONDocument() error = %v", err)
+			}
+			if err := d.Delete(tt.keys...); err != tt.wantErr {
+				t.Fatalf("Delete() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := string(d.Bytes()); got != tt.want {
+				t.Errorf("Delete() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
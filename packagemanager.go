@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/pathutil"
+)
+
+// PackageManager is a node package manager used to install the project's dependencies.
+type PackageManager string
+
+const (
+	// PackageManagerAuto detects the package manager from the project files.
+	PackageManagerAuto PackageManager = "auto"
+	// PackageManagerNpm ...
+	PackageManagerNpm PackageManager = "npm"
+	// PackageManagerYarn is Yarn 1 (Classic).
+	PackageManagerYarn PackageManager = "yarn"
+	// PackageManagerYarnBerry is Yarn 2 and above (Berry).
+	PackageManagerYarnBerry PackageManager = "yarn-berry"
+	// PackageManagerPnpm ...
+	PackageManagerPnpm PackageManager = "pnpm"
+	// PackageManagerBun ...
+	PackageManagerBun PackageManager = "bun"
+)
+
+// lockfiles maps the lockfiles to their package managers, in detection order.
+var lockfiles = []struct {
+	name    string
+	manager PackageManager
+}{
+	{"pnpm-lock.yaml", PackageManagerPnpm},
+	{"bun.lockb", PackageManagerBun},
+	{"bun.lock", PackageManagerBun},
+	{".yarnrc.yml", PackageManagerYarnBerry},
+	{"yarn.lock", PackageManagerYarn},
+	{"package-lock.json", PackageManagerNpm},
+}
+
+// installArgs returns the dependency install command.
+// The install runs after package.json was modified, so the lockfile is updated instead of failing the install.
+func (m PackageManager) installArgs() []string {
+	switch m {
+	case PackageManagerYarn:
+		return []string{"yarn", "install"}
+	case PackageManagerYarnBerry:
+		// Yarn Berry enables immutable installs by default on CI.
+		return []string{"yarn", "install", "--no-immutable"}
+	case PackageManagerPnpm:
+		// pnpm enables frozen lockfile installs by default on CI.
+		return []string{"pnpm", "install", "--no-frozen-lockfile"}
+	case PackageManagerBun:
+		return []string{"bun", "install"}
+	default:
+		return []string{"npm", "install"}
+	}
+}
+
+// selectPackageManager returns the forced package manager, or detects it if forced is auto.
+// The second return value describes why the package manager was selected.
+func selectPackageManager(workdir string, forced PackageManager) (PackageManager, string, error) {
+	switch forced {
+	case PackageManagerAuto, "":
+		return detectPackageManager(workdir)
+	case PackageManagerYarn:
+		detected, reason, err := detectPackageManager(workdir)
+		if err != nil {
+			return "", "", err
+		}
+		if detected == PackageManagerYarnBerry {
+			return PackageManagerYarnBerry, "forced by input, " + reason, nil
+		}
+		return PackageManagerYarn, "forced by input", nil
+	default:
+		return forced, "forced by input", nil
+	}
+}
+
+// detectPackageManager detects the package manager from the packageManager field of package.json,
+// or from the lockfiles in the workdir and its parent directories (for monorepos), up to the repository root.
+func detectPackageManager(workdir string) (PackageManager, string, error) {
+	packages, err := parsePackageJSON(filepath.Join(workdir, "package.json"))
+	if err != nil {
+		return "", "", err
+	}
+	if root, err := packages.Object(); err == nil {
+		if field, err := root.String("packageManager"); err == nil && field != "" {
+			manager, err := parsePackageManagerField(field)
+			if err != nil {
+				return "", "", err
+			}
+			return manager, fmt.Sprintf("packageManager field in package.json: %s", field), nil
+		}
+	}
+
+	dir, err := pathutil.AbsPath(workdir)
+	if err != nil {
+		return "", "", err
+	}
+	for {
+		for _, lockfile := range lockfiles {
+			pth := filepath.Join(dir, lockfile.name)
+			if exist, err := pathutil.IsPathExists(pth); err != nil {
+				return "", "", err
+			} else if exist {
+				return lockfile.manager, fmt.Sprintf("found %s", pth), nil
+			}
+		}
+
+		if isRepoRoot, err := pathutil.IsPathExists(filepath.Join(dir, ".git")); err != nil {
+			return "", "", err
+		} else if isRepoRoot {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	return PackageManagerNpm, "no lockfile found", nil
+}
+
+// parsePackageManagerField parses the Corepack packageManager field (like pnpm@8.6.0 or yarn@3.6.1+sha256.abc).
+func parsePackageManagerField(field string) (PackageManager, error) {
+	split := strings.SplitN(field, "@", 2)
+	name := split[0]
+	version := ""
+	if len(split) == 2 {
+		version = split[1]
+	}
+
+	switch PackageManager(name) {
+	case PackageManagerNpm, PackageManagerPnpm, PackageManagerBun:
+		return PackageManager(name), nil
+	case PackageManagerYarn:
+		if version != "" && !strings.HasPrefix(version, "1.") {
+			return PackageManagerYarnBerry, nil
+		}
+		return PackageManagerYarn, nil
+	default:
+		return "", fmt.Errorf("unsupported packageManager in package.json: %s", field)
+	}
+}
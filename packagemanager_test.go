@@ -0,0 +1,105 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSelectPackageManager(t *testing.T) {
+	const packageJSON = `{"name": "app"}`
+
+	tests := []struct {
+		name string
+		// files are created in the repository root, the project is in the app directory.
+		files  map[string]string
+		forced PackageManager
+		want   PackageManager
+	}{
+		{name: "no lockfile", files: map[string]string{}, want: PackageManagerNpm},
+		{name: "package-lock.json", files: map[string]string{"app/package-lock.json": ""}, want: PackageManagerNpm},
+		{name: "yarn.lock", files: map[string]string{"app/yarn.lock": ""}, want: PackageManagerYarn},
+		{name: "pnpm-lock.yaml", files: map[string]string{"app/pnpm-lock.yaml": "", "app/package-lock.json": ""}, want: PackageManagerPnpm},
+		{name: "bun.lockb", files: map[string]string{"app/bun.lockb": "", "app/yarn.lock": ""}, want: PackageManagerBun},
+		{name: ".yarnrc.yml", files: map[string]string{"app/.yarnrc.yml": "", "app/yarn.lock": ""}, want: PackageManagerYarnBerry},
+		{name: "lockfile in the repository root", files: map[string]string{"pnpm-lock.yaml": ""}, want: PackageManagerPnpm},
+		{
+			name:  "packageManager field",
+			files: map[string]string{"app/package.json": `{"name": "app", "packageManager": "pnpm@8.6.0"}`, "app/yarn.lock": ""},
+			want:  PackageManagerPnpm,
+		},
+		{
+			name:  "packageManager field with yarn 3",
+			files: map[string]string{"app/package.json": `{"name": "app", "packageManager": "yarn@3.6.1+sha256.abc"}`},
+			want:  PackageManagerYarnBerry,
+		},
+		{
+			name:  "packageManager field with yarn 1",
+			files: map[string]string{"app/package.json": `{"name": "app", "packageManager": "yarn@1.22.19"}`},
+			want:  PackageManagerYarn,
+		},
+		{name: "forced npm", files: map[string]string{"app/yarn.lock": ""}, forced: PackageManagerNpm, want: PackageManagerNpm},
+		{name: "forced yarn", files: map[string]string{}, forced: PackageManagerYarn, want: PackageManagerYarn},
+		{name: "forced yarn with yarn berry", files: map[string]string{".yarnrc.yml": ""}, forced: PackageManagerYarn, want: PackageManagerYarnBerry},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string]string{".git/HEAD": "", "app/package.json": packageJSON}
+			for name, content := range tt.files {
+				files[name] = content
+			}
+			root := createProject(t, files)
+
+			got, _, err := selectPackageManager(filepath.Join(root, "app"), tt.forced)
+			if err != nil {
+				t.Fatalf("selectPackageManager() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("selectPackageManager() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectPackageManager_StopsAtRepositoryRoot(t *testing.T) {
+	// The lockfile above the repository root belongs to another project.
+	root := createProject(t, map[string]string{
+		"yarn.lock":             "",
+		"repo/.git/HEAD":        "",
+		"repo/app/package.json": `{"name": "app"}`,
+	})
+
+	got, _, err := detectPackageManager(filepath.Join(root, "repo", "app"))
+	if err != nil {
+		t.Fatalf("detectPackageManager() error = %v", err)
+	}
+	if got != PackageManagerNpm {
+		t.Errorf("detectPackageManager() = %s, want %s", got, PackageManagerNpm)
+	}
+}
+
+func TestParsePackageManagerField(t *testing.T) {
+	tests := []struct {
+		field   string
+		want    PackageManager
+		wantErr bool
+	}{
+		{field: "npm@9.8.1", want: PackageManagerNpm},
+		{field: "pnpm@8.6.0", want: PackageManagerPnpm},
+		{field: "bun@1.0.0", want: PackageManagerBun},
+		{field: "yarn", want: PackageManagerYarn},
+		{field: "yarn@1.22.19", want: PackageManagerYarn},
+		{field: "yarn@4.0.2", want: PackageManagerYarnBerry},
+		{field: "cnpm@1.0.0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			got, err := parsePackageManagerField(tt.field)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePackageManagerField() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parsePackageManagerField() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Phase is a stage of the Step with its own timeout.
+type Phase string
+
+const (
+	// PhaseInstall installs the Expo CLI.
+	PhaseInstall Phase = "install"
+	// PhaseLogin logs in to and out from the Expo account.
+	PhaseLogin Phase = "login"
+	// PhaseEject generates the native projects.
+	PhaseEject Phase = "eject"
+	// PhasePublish publishes the project.
+	PhasePublish Phase = "publish"
+	// PhaseDependencyInstall installs the node dependencies after the package.json changes.
+	PhaseDependencyInstall Phase = "dependency_install"
+)
+
+var phases = []Phase{PhaseInstall, PhaseLogin, PhaseEject, PhasePublish, PhaseDependencyInstall}
+
+// PhaseTimeoutError is returned if a phase did not finish in time.
+type PhaseTimeoutError struct {
+	Phase   Phase
+	Timeout time.Duration
+	Killed  *CommandKilledError
+}
+
+// Error ...
+func (e *PhaseTimeoutError) Error() string {
+	msg := fmt.Sprintf("the %s phase timed out after %s, killed %s", e.Phase, e.Timeout, e.Killed.Cmd)
+	if len(e.Killed.LastLines) > 0 {
+		msg += "\nLast output lines:\n" + strings.Join(e.Killed.LastLines, "\n")
+	}
+	return msg
+}
+
+// parsePhaseTimeouts parses the phase_timeouts input: a phase=duration line per phase, like eject=20m.
+// A zero duration disables the phase's timeout.
+func parsePhaseTimeouts(s string) (map[Phase]time.Duration, error) {
+	timeouts := map[Phase]time.Duration{}
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%s: expected phase=duration", line)
+		}
+		phase, value := Phase(strings.TrimSpace(parts[0])), strings.TrimSpace(parts[1])
+
+		known := false
+		for _, p := range phases {
+			known = known || p == phase
+		}
+		if !known {
+			return nil, fmt.Errorf("%s: unknown phase %s, available phases: %s", line, phase, phases)
+		}
+
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout < 0 {
+			return nil, fmt.Errorf("%s: invalid duration %s, use a duration like 90s or 20m", line, value)
+		}
+		timeouts[phase] = timeout
+	}
+	return timeouts, nil
+}
+
+// inPhase runs fn with the phase's timeout, a command killed because of the timeout is reported as a *PhaseTimeoutError.
+func (e Expo) inPhase(ctx context.Context, phase Phase, fn func(ctx context.Context) error) error {
+	timeout := e.Timeouts[phase]
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+
+	phaseCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := fn(phaseCtx)
+	if killed, ok := err.(*CommandKilledError); ok && killed.Err == context.DeadlineExceeded && ctx.Err() == nil {
+		return &PhaseTimeoutError{Phase: phase, Timeout: timeout, Killed: killed}
+	}
+	return err
+}
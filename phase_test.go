@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePhaseTimeouts(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[Phase]time.Duration
+		wantErr bool
+	}{
+		{
+			name:  "phases",
+			input: "install=10m\n# no login timeout\nlogin=0\n eject = 1h30m \n",
+			want:  map[Phase]time.Duration{PhaseInstall: 10 * time.Minute, PhaseLogin: 0, PhaseEject: 90 * time.Minute},
+		},
+		{name: "empty", input: "", want: map[Phase]time.Duration{}},
+		{name: "unknown phase", input: "build=10m", wantErr: true},
+		{name: "invalid duration", input: "eject=10", wantErr: true},
+		{name: "missing duration", input: "eject", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePhaseTimeouts(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePhaseTimeouts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePhaseTimeouts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpoInPhase_KillsProcessGroupOnTimeout(t *testing.T) {
+	e := Expo{
+		Runner:   newDefaultCommandRunner("s3cr3t"),
+		Timeouts: map[Phase]time.Duration{PhaseEject: 500 * time.Millisecond},
+	}
+	// The background sleep keeps the output open, it exits only if the whole process group is killed.
+	cmd := NewCommand("sh", "-c", "echo 'Waiting for input'; echo 'token: s3cr3t'; sleep 30 & wait")
+
+	start := time.Now()
+	err := e.inPhase(context.Background(), PhaseEject, func(ctx context.Context) error {
+		_, err := e.Runner.CombinedOutput(ctx, cmd)
+		return err
+	})
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("the command was not killed in time: %s", elapsed)
+	}
+
+	timeoutErr, ok := err.(*PhaseTimeoutError)
+	if !ok {
+		t.Fatalf("inPhase() error = %v, want *PhaseTimeoutError", err)
+	}
+	if timeoutErr.Phase != PhaseEject || timeoutErr.Timeout != 500*time.Millisecond {
+		t.Errorf("phase, timeout = %s, %s", timeoutErr.Phase, timeoutErr.Timeout)
+	}
+	if want := []string{"Waiting for input", "token: [REDACTED]"}; !reflect.DeepEqual(timeoutErr.Killed.LastLines, want) {
+		t.Errorf("last lines = %q, want %q", timeoutErr.Killed.LastLines, want)
+	}
+}
+
+func TestExpoInPhase_NoTimeout(t *testing.T) {
+	e := Expo{Runner: newDefaultCommandRunner()}
+
+	err := e.inPhase(context.Background(), PhaseEject, func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); ok {
+			t.Error("context has a deadline")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("inPhase() error = %v", err)
+	}
+}
+
+func TestResolveExpoConfig_Timeout(t *testing.T) {
+	workdir := createProject(t, projectFiles)
+	e := newTestExpo(workdir, newDefaultCommandRunner())
+	// A dynamic config waiting for input, the extra arguments are the script's positional parameters.
+	e.CLI = ExpoCLI{Command: []string{"sh", "-c", "sleep 30 & wait", "sh"}}
+	e.Timeouts = map[Phase]time.Duration{PhaseEject: 500 * time.Millisecond}
+
+	start := time.Now()
+	config, err := e.resolveExpoConfig(context.Background())
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("expo config was not killed in time: %s", elapsed)
+	}
+	if err != nil {
+		t.Fatalf("resolveExpoConfig() error = %v", err)
+	}
+	if config.Name != "app" {
+		t.Errorf("name = %s, want the static config's name", config.Name)
+	}
+}
+
+func TestExpoLogin_KilledRedactsPassword(t *testing.T) {
+	const password = "hunter2pw"
+
+	tests := []struct {
+		name     string
+		timeouts map[Phase]time.Duration
+		// cancelAfter cancels the Step's context, like a SIGTERM.
+		cancelAfter time.Duration
+	}{
+		{name: "login phase timeout", timeouts: map[Phase]time.Duration{PhaseLogin: 300 * time.Millisecond}},
+		{name: "step cancelled", cancelAfter: 300 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestExpo(createProject(t, nil), newDefaultCommandRunner(password))
+			// A login waiting for input, the extra arguments are the script's positional parameters.
+			e.CLI = ExpoCLI{Command: []string{"sh", "-c", "sleep 30 & wait", "sh"}}
+			e.Timeouts = tt.timeouts
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancelAfter > 0 {
+				time.AfterFunc(tt.cancelAfter, cancel)
+			}
+
+			err := e.login(ctx, "user", password)
+			if err == nil {
+				t.Fatal("login() error = nil, want the killed command")
+			}
+			if strings.Contains(err.Error(), password) {
+				t.Errorf("login() error = %q, contains the password", err)
+			}
+			if !strings.Contains(err.Error(), redacted) {
+				t.Errorf("login() error = %q, want the redacted command line", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-tools/go-steputils/stepconf"
+)
+
+// Plan collects the commands and file changes of a dry run, in order, with the secrets redacted.
+type Plan struct {
+	secrets []string
+	steps   []string
+}
+
+// NewPlan ...
+func NewPlan(secrets ...stepconf.Secret) *Plan {
+	return &Plan{secrets: secretValues(secrets...)}
+}
+
+func (p *Plan) redact(s string) string {
+	return redactSecrets(s, p.secrets)
+}
+
+// AddCommand records a command instead of running it.
+func (p *Plan) AddCommand(cmd Command) {
+	step := "$ " + cmd.String()
+	if cmd.Dir != "" {
+		step += fmt.Sprintf(" (in %s)", cmd.Dir)
+	}
+	p.steps = append(p.steps, p.redact(step))
+}
+
+// AddFileChange records a file modification as a unified diff instead of writing the file.
+func (p *Plan) AddFileChange(name string, before, after []byte) {
+	diff := unifiedDiff(name, string(before), string(after))
+	if diff == "" {
+		p.AddNote("%s is not changed", name)
+		return
+	}
+	p.steps = append(p.steps, p.redact(fmt.Sprintf("modify %s:\n%s", name, diff)))
+}
+
+// AddNote records a step which is not a command or a file change.
+func (p *Plan) AddNote(format string, v ...interface{}) {
+	p.steps = append(p.steps, p.redact(fmt.Sprintf(format, v...)))
+}
+
+// Print prints the numbered steps of the plan.
+func (p *Plan) Print() {
+	log.Infof("Dry run plan:")
+	for i, step := range p.steps {
+		log.Printf("%d. %s", i+1, step)
+	}
+}
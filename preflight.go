@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// NodeVersionCheck selects what to do if the Node.js or npm version does not meet the requirements.
+type NodeVersionCheck string
+
+const (
+	// NodeVersionCheckFail fails the Step before the Expo CLI is installed.
+	NodeVersionCheckFail NodeVersionCheck = "fail"
+	// NodeVersionCheckWarn only prints the issues.
+	NodeVersionCheckWarn NodeVersionCheck = "warn"
+)
+
+// toolRequirement is a version range required for a tool by the project or the Expo CLI.
+type toolRequirement struct {
+	Tool  string
+	Range string
+	// Source is the requirement's origin, like package.json engines.
+	Source string
+}
+
+// expoCLIRequirements returns the Node.js and npm versions required by the Expo CLI used in the eject mode.
+func expoCLIRequirements(mode EjectMode, expoCLIVersion string, sdkVersion int) []toolRequirement {
+	if mode == EjectModePrebuild {
+		source := fmt.Sprintf("@expo/cli of Expo SDK %d", sdkVersion)
+		node := ">=14"
+		switch {
+		case sdkVersion >= 52:
+			node = ">=18.18"
+		case sdkVersion >= 50:
+			node = ">=18"
+		}
+		return []toolRequirement{{Tool: "node", Range: node, Source: source}}
+	}
+
+	source := "expo-cli " + expoCLIVersion
+	node := ">=12.13"
+	if major, err := strconv.Atoi(strings.SplitN(expoCLIVersion, ".", 2)[0]); err == nil && major < 4 {
+		node = ">=10.13"
+	}
+	return []toolRequirement{
+		{Tool: "node", Range: node, Source: source},
+		{Tool: "npm", Range: ">=6", Source: source},
+	}
+}
+
+// engineRequirements returns the Node.js and npm versions required by the engines field of package.json.
+func engineRequirements(workdir string) ([]toolRequirement, error) {
+	packages, err := parsePackageJSON(filepath.Join(workdir, "package.json"))
+	if err != nil {
+		return nil, err
+	}
+	engines, err := packages.Object("engines")
+	if err != nil {
+		return nil, nil
+	}
+
+	var requirements []toolRequirement
+	for _, tool := range []string{"node", "npm"} {
+		if r, err := engines.String(tool); err == nil && r != "" {
+			requirements = append(requirements, toolRequirement{Tool: tool, Range: r, Source: "package.json engines"})
+		}
+	}
+	return requirements, nil
+}
+
+// toolVersion returns the version printed by the tool's --version flag.
+func (e Expo) toolVersion(ctx context.Context, tool string) (semanticVersion, error) {
+	cmd := NewCommand(tool, "--version")
+	cmd.Dir = e.Workdir
+
+	out, err := e.Runner.Output(ctx, cmd)
+	if err != nil {
+		return semanticVersion{}, fmt.Errorf("%s failed: %s", cmd, err)
+	}
+	lines := strings.Split(out, "\n")
+	return parseSemanticVersion(strings.TrimSpace(lines[len(lines)-1]))
+}
+
+// checkNodeVersions compares the installed Node.js and npm versions with the requirements,
+// and returns the unmet requirements. A tool whose version can not be determined is reported as an issue too,
+// so it only fails the Step in fail mode.
+func (e Expo) checkNodeVersions(ctx context.Context, requirements []toolRequirement) []string {
+	var issues []string
+	versions := map[string]semanticVersion{}
+	for _, tool := range []string{"node", "npm"} {
+		version, err := e.toolVersion(ctx, tool)
+		if err != nil {
+			issues = append(issues, fmt.Sprintf("failed to get the %s version: %s", tool, err))
+			continue
+		}
+		log.Printf("%s version: %s", tool, version)
+		versions[tool] = version
+	}
+
+	for _, requirement := range requirements {
+		r, err := parseVersionRange(requirement.Range)
+		if err != nil {
+			log.Warnf("Skipping the %s requirement of %s: %s", requirement.Tool, requirement.Source, err)
+			continue
+		}
+		version, ok := versions[requirement.Tool]
+		if !ok {
+			continue
+		}
+		if !r.Contains(version) {
+			issues = append(issues, fmt.Sprintf("%s %s does not satisfy %s required by %s", requirement.Tool, version, requirement.Range, requirement.Source))
+		}
+	}
+	return issues
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestExpoCheckNodeVersions(t *testing.T) {
+	workdir := createProject(t, map[string]string{
+		"package.json": `{"dependencies": {"expo": "~39.0.2"}, "engines": {"node": ">=14 <17", "npm": "lts"}}`,
+	})
+
+	runner := newFakeCommandRunner()
+	runner.outputs["node --version"] = "v12.22.12"
+	runner.outputs["npm --version"] = "6.14.16"
+
+	e := newTestExpo(workdir, runner)
+	requirements := expoCLIRequirements(EjectModeEject, "3.28.0", 39)
+	engines, err := engineRequirements(workdir)
+	if err != nil {
+		t.Fatalf("engineRequirements() error = %v", err)
+	}
+	requirements = append(requirements, engines...)
+
+	issues := e.checkNodeVersions(context.Background(), requirements)
+
+	// The npm engine range is invalid, it is skipped.
+	want := []string{
+		"node 12.22.12 does not satisfy >=14 <17 required by package.json engines",
+	}
+	if !reflect.DeepEqual(issues, want) {
+		t.Errorf("issues = %q, want %q", issues, want)
+	}
+}
+
+func TestExpoCheckNodeVersions_VersionQueryFails(t *testing.T) {
+	runner := newFakeCommandRunner()
+	runner.outputs["node --version"] = "v18.19.0"
+	runner.errors["npm --version"] = errors.New("exit status 127")
+
+	e := newTestExpo("/project", runner)
+	issues := e.checkNodeVersions(context.Background(), expoCLIRequirements(EjectModeEject, "latest", 44))
+
+	// The npm requirement can not be checked, only the failed query is reported.
+	want := []string{`failed to get the npm version: npm "--version" failed: exit status 127`}
+	if !reflect.DeepEqual(issues, want) {
+		t.Errorf("issues = %q, want %q", issues, want)
+	}
+}
+
+func TestExpoCLIRequirements(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       EjectMode
+		cliVersion string
+		sdkVersion int
+		wantNode   string
+	}{
+		{"expo-cli 3", EjectModeEject, "3.28.0", 38, ">=10.13"},
+		{"latest expo-cli", EjectModeEject, "latest", 44, ">=12.13"},
+		{"SDK 49 prebuild", EjectModePrebuild, "latest", 49, ">=14"},
+		{"SDK 50 prebuild", EjectModePrebuild, "latest", 50, ">=18"},
+		{"SDK 53 prebuild", EjectModePrebuild, "latest", 53, ">=18.18"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requirements := expoCLIRequirements(tt.mode, tt.cliVersion, tt.sdkVersion)
+			if requirements[0].Tool != "node" || requirements[0].Range != tt.wantNode {
+				t.Errorf("node requirement = %+v, want %s", requirements[0], tt.wantNode)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"regexp"
+	"strings"
+)
+
+const (
+	publishManifestURLKey = "EXPO_PUBLISH_MANIFEST_URL"
+	publishBundleURLsKey  = "EXPO_PUBLISH_BUNDLE_URLS"
+	publishRevisionIDKey  = "EXPO_PUBLISH_REVISION_ID"
+)
+
+var (
+	urlPattern = regexp.MustCompile(`https?://[^\s"']+`)
+	// expo-cli 3 prints the URL after a "Your URL is" line, expo-cli 4 prints a "Manifest: <url>" line.
+	manifestURLPattern = regexp.MustCompile(`(?i)manifest(?: url)?:\s*(https?://[^\s"']+)`)
+	bundleURLPattern   = regexp.MustCompile(`https?://[^\s"']+\.(?:js|bundle)\b`)
+	revisionIDPattern  = regexp.MustCompile(`(?i)(?:revision ?id|"revisionId")["']?\s*[:=]\s*["']?([\w.-]+)`)
+)
+
+// PublishResult holds the locations of a published update.
+type PublishResult struct {
+	ManifestURL string
+	BundleURLs  []string
+	RevisionID  string
+}
+
+// parsePublishOutput extracts the manifest URL, the bundle URLs and the revision ID from the expo publish output.
+func parsePublishOutput(out string) PublishResult {
+	var result PublishResult
+
+	expectURL := false
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if match := manifestURLPattern.FindStringSubmatch(line); match != nil && result.ManifestURL == "" {
+			result.ManifestURL = match[1]
+		} else if expectURL && result.ManifestURL == "" {
+			if url := urlPattern.FindString(line); url != "" {
+				result.ManifestURL = url
+				expectURL = false
+			}
+		}
+		if strings.Contains(strings.ToLower(line), "your url is") {
+			expectURL = true
+		}
+
+		for _, url := range bundleURLPattern.FindAllString(line, -1) {
+			if !sliceContains(result.BundleURLs, url) {
+				result.BundleURLs = append(result.BundleURLs, url)
+			}
+		}
+
+		if match := revisionIDPattern.FindStringSubmatch(line); match != nil && result.RevisionID == "" {
+			result.RevisionID = match[1]
+		}
+	}
+
+	return result
+}
+
+func sliceContains(s []string, value string) bool {
+	for _, v := range s {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// exportPublishResult exports the found publish locations, the bundle URLs are separated by |.
+func exportPublishResult(result PublishResult) error {
+	return exportOutputs(
+		output{publishManifestURLKey, result.ManifestURL},
+		output{publishBundleURLsKey, strings.Join(result.BundleURLs, "|")},
+		output{publishRevisionIDKey, result.RevisionID},
+	)
+}
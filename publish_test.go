@@ -0,0 +1,55 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePublishOutput(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		want PublishResult
+	}{
+		{
+			name: "expo-cli 4",
+			out: `Building optimized bundles and generating sourcemaps...
+Uploading JavaScript bundles
+iOS bundle: https://d1wp6m56sqw74a.cloudfront.net/%40user%2Fapp%2F1.0.0%2Fabc-39.0.0-ios.js
+Android bundle: https://d1wp6m56sqw74a.cloudfront.net/%40user%2Fapp%2F1.0.0%2Fdef-39.0.0-android.js
+Publish complete
+
+📝  Manifest: https://exp.host/@user/app/index.exp?sdkVersion=39.0.0&release-channel=staging Learn more: https://expo.fyi/manifest-url
+⚙️   Project page: https://expo.io/@user/projects/app Learn more: https://expo.fyi/project-page
+Revision ID: 9a0fd35b-d0d4-4e4f-a21b-0ef1b1d8c2e4`,
+			want: PublishResult{
+				ManifestURL: "https://exp.host/@user/app/index.exp?sdkVersion=39.0.0&release-channel=staging",
+				BundleURLs: []string{
+					"https://d1wp6m56sqw74a.cloudfront.net/%40user%2Fapp%2F1.0.0%2Fabc-39.0.0-ios.js",
+					"https://d1wp6m56sqw74a.cloudfront.net/%40user%2Fapp%2F1.0.0%2Fdef-39.0.0-android.js",
+				},
+				RevisionID: "9a0fd35b-d0d4-4e4f-a21b-0ef1b1d8c2e4",
+			},
+		},
+		{
+			name: "expo-cli 3",
+			out: `Published
+Your URL is
+
+https://exp.host/@user/app?release-channel=staging`,
+			want: PublishResult{ManifestURL: "https://exp.host/@user/app?release-channel=staging"},
+		},
+		{
+			name: "no publish info",
+			out:  "Uploading JavaScript bundles",
+			want: PublishResult{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parsePublishOutput(tt.out); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePublishOutput() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bitrise-tools/go-steputils/stepconf"
+)
+
+const redacted = "[REDACTED]"
+
+// secretValues returns the non-empty secrets, the longest first, so a secret containing another one is masked as a whole.
+func secretValues(secrets ...stepconf.Secret) []string {
+	var values []string
+	for _, secret := range secrets {
+		if secret != "" {
+			values = append(values, string(secret))
+		}
+	}
+	sort.SliceStable(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+	return values
+}
+
+// redactSecrets masks the secrets in s.
+func redactSecrets(s string, secrets []string) string {
+	for _, secret := range secrets {
+		s = strings.Replace(s, secret, redacted, -1)
+	}
+	return s
+}
+
+// redactWriter masks the secrets in the output written to it.
+// Output which may be the beginning of a secret split across writes is held back until the next write or Close.
+type redactWriter struct {
+	w       io.Writer
+	secrets [][]byte
+
+	mu      sync.Mutex
+	pending []byte
+}
+
+func newRedactWriter(w io.Writer, secrets []string) *redactWriter {
+	r := &redactWriter{w: w}
+	for _, secret := range secrets {
+		r.secrets = append(r.secrets, []byte(secret))
+	}
+	return r
+}
+
+// Write ...
+func (r *redactWriter) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pending = append(r.pending, p...)
+	if err := r.flush(false); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close writes the held back output, it does not close the underlying writer.
+func (r *redactWriter) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.flush(true)
+}
+
+// flush writes the pending output with the secrets masked, holding back the possible beginning of a secret unless final.
+func (r *redactWriter) flush(final bool) error {
+	var out bytes.Buffer
+	for {
+		start, secret := r.nextSecret()
+		if secret == nil {
+			break
+		}
+		// A longer secret may start with the found one, its remaining part may come with the next write.
+		if !final && r.partialSecretAt(start) {
+			break
+		}
+		out.Write(r.pending[:start])
+		out.WriteString(redacted)
+		r.pending = r.pending[start+len(secret):]
+	}
+
+	keep := 0
+	if !final {
+		keep = r.partialSecretSuffix()
+	}
+	out.Write(r.pending[:len(r.pending)-keep])
+	r.pending = append([]byte{}, r.pending[len(r.pending)-keep:]...)
+
+	if out.Len() == 0 {
+		return nil
+	}
+	_, err := r.w.Write(out.Bytes())
+	return err
+}
+
+// nextSecret returns the first secret occurrence in the pending output, the longest one if multiple start at the same offset.
+func (r *redactWriter) nextSecret() (int, []byte) {
+	start, found := -1, []byte(nil)
+	for _, secret := range r.secrets {
+		idx := bytes.Index(r.pending, secret)
+		if idx == -1 {
+			continue
+		}
+		if start == -1 || idx < start || (idx == start && len(secret) > len(found)) {
+			start, found = idx, secret
+		}
+	}
+	return start, found
+}
+
+// partialSecretAt reports whether the pending output from start is the beginning of a secret.
+func (r *redactWriter) partialSecretAt(start int) bool {
+	rest := r.pending[start:]
+	for _, secret := range r.secrets {
+		if len(rest) < len(secret) && bytes.HasPrefix(secret, rest) {
+			return true
+		}
+	}
+	return false
+}
+
+// partialSecretSuffix returns the length of the longest pending suffix which is a prefix of a secret.
+func (r *redactWriter) partialSecretSuffix() int {
+	longest := 0
+	for _, secret := range r.secrets {
+		n := len(secret) - 1
+		if n > len(r.pending) {
+			n = len(r.pending)
+		}
+		for ; n > longest; n-- {
+			if bytes.HasSuffix(r.pending, secret[:n]) {
+				longest = n
+				break
+			}
+		}
+	}
+	return longest
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRedactWriter(t *testing.T) {
+	secrets := secretValues("s3cr3t", "tok", "tok-en-123")
+
+	tests := []struct {
+		name   string
+		writes []string
+		want   string
+	}{
+		{
+			name:   "secret in a single write",
+			writes: []string{"password: s3cr3t\n"},
+			want:   "password: [REDACTED]\n",
+		},
+		{
+			name:   "secret split across writes",
+			writes: []string{"password: s3", "c", "r3t and s3cr", "3t\n"},
+			want:   "password: [REDACTED] and [REDACTED]\n",
+		},
+		{
+			name:   "longer secret containing a shorter one",
+			writes: []string{"EXPO_TOKEN=tok-en", "-123, tok"},
+			want:   "EXPO_TOKEN=[REDACTED], [REDACTED]",
+		},
+		{
+			name:   "held back prefix flushed on close",
+			writes: []string{"exit status 1: s3cr"},
+			want:   "exit status 1: s3cr",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			w := newRedactWriter(&out, secrets)
+			for _, s := range tt.writes {
+				if n, err := w.Write([]byte(s)); err != nil || n != len(s) {
+					t.Fatalf("Write() = %d, %v", n, err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactWriter_ByteByByte(t *testing.T) {
+	const secret = "abcabd"
+	const input = "xxabcabcabdyy abcab"
+
+	var out bytes.Buffer
+	w := newRedactWriter(&out, []string{secret})
+	for i := 0; i < len(input); i++ {
+		if _, err := w.Write([]byte{input[i]}); err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(out.Bytes(), []byte(secret)) {
+			t.Fatalf("secret written after %d bytes: %q", i+1, out.String())
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "xxabc[REDACTED]yy abcab"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-tools/go-steputils/stepconf"
+)
+
+const (
+	// npmAuthTokenEnvKey is referenced by the written registry configs, so the token is never written to disk.
+	npmAuthTokenEnvKey  = "NPM_AUTH_TOKEN"
+	defaultNPMRegistry  = "https://registry.npmjs.org/"
+	registryConfigStart = "# Private registry config of the Expo Eject Step, removed when the Step finishes"
+)
+
+// scopedRegistry is the registry of the packages of an npm scope.
+type scopedRegistry struct {
+	Scope string
+	URL   string
+}
+
+// NPMRegistry is the npm registry config of the project's package installs.
+type NPMRegistry struct {
+	// URL is the registry of the unscoped packages, the package managers' default if empty.
+	URL    string
+	Scopes []scopedRegistry
+	// AuthToken authenticates every configured registry, or the default registry if only the token is set.
+	AuthToken stepconf.Secret
+}
+
+// configured reports whether the registry config differs from the package managers' default.
+func (r NPMRegistry) configured() bool {
+	return r.URL != "" || len(r.Scopes) > 0 || r.AuthToken != ""
+}
+
+var npmScopePattern = regexp.MustCompile(`^@[a-z0-9][a-z0-9._-]*$`)
+
+// parseScopedRegistries parses the scoped registries input: a @scope=url line per scope.
+func parseScopedRegistries(s string) ([]scopedRegistry, error) {
+	var registries []scopedRegistry
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%s: expected @scope=url", line)
+		}
+		registry := scopedRegistry{Scope: strings.TrimSpace(parts[0]), URL: strings.TrimSpace(parts[1])}
+		if !npmScopePattern.MatchString(registry.Scope) {
+			return nil, fmt.Errorf("%s: invalid scope %s, expected a scope like @company", line, registry.Scope)
+		}
+		if err := validateRegistryURL(registry.URL); err != nil {
+			return nil, fmt.Errorf("%s: %s", line, err)
+		}
+		registries = append(registries, registry)
+	}
+	return registries, nil
+}
+
+func validateRegistryURL(u string) error {
+	parsed, err := url.Parse(u)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid registry URL %s, expected an http or https URL", u)
+	}
+	return nil
+}
+
+// registryAuthPrefix returns the npmrc key prefix of the registry's settings, like //npm.company.com/path/.
+func registryAuthPrefix(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+	return "//" + parsed.Host + strings.TrimSuffix(parsed.Path, "/") + "/"
+}
+
+// withTrailingSlash returns the registry URL ending with a slash, npm resolves the package paths relative to it.
+func withTrailingSlash(u string) string {
+	return strings.TrimSuffix(u, "/") + "/"
+}
+
+// npmrc returns the project .npmrc content with the registry config appended to the existing content.
+// It is read by npm, Yarn 1, pnpm and Bun.
+func (r NPMRegistry) npmrc(existing []byte) []byte {
+	var b bytes.Buffer
+	b.Write(existing)
+	if len(existing) > 0 && !bytes.HasSuffix(existing, []byte("\n")) {
+		b.WriteString("\n")
+	}
+	b.WriteString(registryConfigStart + "\n")
+
+	if r.URL != "" {
+		fmt.Fprintf(&b, "registry=%s\n", withTrailingSlash(r.URL))
+	}
+	for _, scope := range r.Scopes {
+		fmt.Fprintf(&b, "%s:registry=%s\n", scope.Scope, withTrailingSlash(scope.URL))
+	}
+
+	if r.AuthToken != "" {
+		for _, u := range r.authenticatedURLs() {
+			fmt.Fprintf(&b, "%s:_authToken=${%s}\n", registryAuthPrefix(u), npmAuthTokenEnvKey)
+		}
+	}
+	return b.Bytes()
+}
+
+// authenticatedURLs returns the registries the token is sent to.
+func (r NPMRegistry) authenticatedURLs() []string {
+	main := r.URL
+	if main == "" {
+		main = defaultNPMRegistry
+	}
+	urls := []string{main}
+	for _, scope := range r.Scopes {
+		if !sliceContains(urls, scope.URL) {
+			urls = append(urls, scope.URL)
+		}
+	}
+	return urls
+}
+
+var yarnrcRegistryKeys = []string{"npmRegistryServer", "npmAuthToken", "npmScopes"}
+
+// yarnrc returns the project .yarnrc.yml content of Yarn 2+ with the registry settings replaced.
+func (r NPMRegistry) yarnrc(existing []byte) []byte {
+	var b bytes.Buffer
+
+	// The top level registry keys are dropped with their nested lines, the other settings are kept.
+	skipping := false
+	for _, line := range strings.SplitAfter(string(existing), "\n") {
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			skipping = false
+			for _, key := range yarnrcRegistryKeys {
+				if strings.HasPrefix(line, key+":") {
+					skipping = true
+				}
+			}
+		}
+		if !skipping {
+			b.WriteString(line)
+		}
+	}
+	if b.Len() > 0 && !bytes.HasSuffix(b.Bytes(), []byte("\n")) {
+		b.WriteString("\n")
+	}
+
+	token := fmt.Sprintf("\"${%s}\"", npmAuthTokenEnvKey)
+	b.WriteString(registryConfigStart + "\n")
+	if r.URL != "" {
+		fmt.Fprintf(&b, "npmRegistryServer: %q\n", strings.TrimSuffix(r.URL, "/"))
+	}
+	if r.AuthToken != "" {
+		fmt.Fprintf(&b, "npmAuthToken: %s\n", token)
+	}
+	if len(r.Scopes) > 0 {
+		b.WriteString("npmScopes:\n")
+		for _, scope := range r.Scopes {
+			fmt.Fprintf(&b, "  %s:\n", strings.TrimPrefix(scope.Scope, "@"))
+			fmt.Fprintf(&b, "    npmRegistryServer: %q\n", strings.TrimSuffix(scope.URL, "/"))
+			if r.AuthToken != "" {
+				fmt.Fprintf(&b, "    npmAuthToken: %s\n", token)
+			}
+		}
+	}
+	return b.Bytes()
+}
+
+// writeRegistryConfig writes the registry config into the project's .npmrc (and .yarnrc.yml for Yarn 2+) files.
+// The cleanup restores the original files. With a plan, the diffs are planned and the files are left untouched.
+func writeRegistryConfig(workdir string, r NPMRegistry, yarnBerry bool, plan *Plan, cleanup *cleanupStack) error {
+	files := []struct {
+		name    string
+		content func(existing []byte) []byte
+	}{
+		{".npmrc", r.npmrc},
+	}
+	if yarnBerry {
+		files = append(files, struct {
+			name    string
+			content func(existing []byte) []byte
+		}{".yarnrc.yml", r.yarnrc})
+	}
+
+	for _, file := range files {
+		pth := filepath.Join(workdir, file.name)
+
+		exist, err := pathutil.IsPathExists(pth)
+		if err != nil {
+			return err
+		}
+		var original []byte
+		if exist {
+			if original, err = fileutil.ReadBytesFromFile(pth); err != nil {
+				return fmt.Errorf("Failed to read %s: %s", file.name, err)
+			}
+		}
+		content := file.content(original)
+
+		if plan != nil {
+			plan.AddFileChange(file.name, original, content)
+			plan.AddNote("restore %s when the Step finishes", file.name)
+			continue
+		}
+
+		name := file.name
+		cleanup.push("restore "+name, func() {
+			if err := restoreFile(pth, original, exist); err != nil {
+				log.Warnf("Failed to restore %s: %s", name, err)
+			}
+		})
+		if err := fileutil.WriteBytesToFile(pth, content); err != nil {
+			return fmt.Errorf("Failed to write %s: %s", file.name, err)
+		}
+		log.Printf("Registry config written to %s", pth)
+	}
+	return nil
+}
+
+// restoreFile writes back the original content, or removes the file if it did not exist.
+func restoreFile(pth string, original []byte, existed bool) error {
+	if !existed {
+		return os.Remove(pth)
+	}
+	return fileutil.WriteBytesToFile(pth, original)
+}
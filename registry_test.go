@@ -0,0 +1,235 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-io/go-utils/pathutil"
+)
+
+func TestParseScopedRegistries(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []scopedRegistry
+		wantErr bool
+	}{
+		{name: "empty", input: ""},
+		{
+			name:  "scopes",
+			input: "@company=https://npm.company.com/\n\n# comment\n @other = https://npm.pkg.github.com \n",
+			want: []scopedRegistry{
+				{Scope: "@company", URL: "https://npm.company.com/"},
+				{Scope: "@other", URL: "https://npm.pkg.github.com"},
+			},
+		},
+		{name: "missing url", input: "@company", wantErr: true},
+		{name: "missing @", input: "company=https://npm.company.com/", wantErr: true},
+		{name: "invalid url", input: "@company=npm.company.com", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseScopedRegistries(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseScopedRegistries() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseScopedRegistries() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNPMRegistry_npmrc(t *testing.T) {
+	registry := NPMRegistry{
+		URL:       "https://npm.company.com/api/npm",
+		Scopes:    []scopedRegistry{{Scope: "@company", URL: "https://npm.pkg.github.com"}},
+		AuthToken: "secret-token",
+	}
+
+	got := string(registry.npmrc([]byte("save-exact=true")))
+	want := `save-exact=true
+` + registryConfigStart + `
+registry=https://npm.company.com/api/npm/
+@company:registry=https://npm.pkg.github.com/
+//npm.company.com/api/npm/:_authToken=${NPM_AUTH_TOKEN}
+//npm.pkg.github.com/:_authToken=${NPM_AUTH_TOKEN}
+`
+	if got != want {
+		t.Errorf("npmrc() = %s, want %s", got, want)
+	}
+
+	got = string(NPMRegistry{AuthToken: "secret-token"}.npmrc(nil))
+	if !strings.Contains(got, "//registry.npmjs.org/:_authToken=${NPM_AUTH_TOKEN}") {
+		t.Errorf("npmrc() = %s, want the default registry authenticated", got)
+	}
+}
+
+func TestNPMRegistry_yarnrc(t *testing.T) {
+	registry := NPMRegistry{
+		URL:       "https://npm.company.com/",
+		Scopes:    []scopedRegistry{{Scope: "@company", URL: "https://npm.pkg.github.com/"}},
+		AuthToken: "secret-token",
+	}
+	existing := `nodeLinker: node-modules
+npmRegistryServer: "https://registry.yarnpkg.com"
+npmScopes:
+  old:
+    npmRegistryServer: "https://old.com"
+yarnPath: .yarn/releases/yarn-3.6.0.cjs`
+
+	got := string(registry.yarnrc([]byte(existing)))
+	want := `nodeLinker: node-modules
+yarnPath: .yarn/releases/yarn-3.6.0.cjs
+` + registryConfigStart + `
+npmRegistryServer: "https://npm.company.com"
+npmAuthToken: "${NPM_AUTH_TOKEN}"
+npmScopes:
+  company:
+    npmRegistryServer: "https://npm.pkg.github.com"
+    npmAuthToken: "${NPM_AUTH_TOKEN}"
+`
+	if got != want {
+		t.Errorf("yarnrc() = %s, want %s", got, want)
+	}
+}
+
+func TestWriteRegistryConfig(t *testing.T) {
+	workdir, err := ioutil.TempDir("", "registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(workdir)
+
+	npmrc := filepath.Join(workdir, ".npmrc")
+	yarnrc := filepath.Join(workdir, ".yarnrc.yml")
+	if err := fileutil.WriteStringToFile(npmrc, "save-exact=true\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := NPMRegistry{URL: "https://npm.company.com/", AuthToken: "secret-token"}
+	cleanup := &cleanupStack{}
+	if err := writeRegistryConfig(workdir, registry, true, nil, cleanup); err != nil {
+		t.Fatalf("writeRegistryConfig() error = %v", err)
+	}
+
+	for _, pth := range []string{npmrc, yarnrc} {
+		content, err := fileutil.ReadStringFromFile(pth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(content, "npm.company.com") {
+			t.Errorf("%s = %s, want the registry", pth, content)
+		}
+		if strings.Contains(content, string(registry.AuthToken)) {
+			t.Errorf("%s = %s, contains the token", pth, content)
+		}
+	}
+
+	cleanup.run()
+
+	if content, err := fileutil.ReadStringFromFile(npmrc); err != nil || content != "save-exact=true\n" {
+		t.Errorf(".npmrc = %q (%v), want the original content", content, err)
+	}
+	if exist, err := pathutil.IsPathExists(yarnrc); err != nil || exist {
+		t.Errorf(".yarnrc.yml exists = %v (%v), want removed", exist, err)
+	}
+}
+
+// TestNPMInstall_PrivateRegistry installs a package with npm from a local stand-in registry, which requires the token.
+func TestNPMInstall_PrivateRegistry(t *testing.T) {
+	if _, err := exec.LookPath("npm"); err != nil {
+		t.Skip("npm not found in PATH")
+	}
+
+	const token = "secret-registry-token"
+	tarball := packageTarball(t, "@company/private", "1.0.0")
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/@company%2fprivate", "/@company/private":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"name":"@company/private","dist-tags":{"latest":"1.0.0"},"versions":{"1.0.0":{"name":"@company/private","version":"1.0.0","dist":{"tarball":"%s/@company/private/-/private-1.0.0.tgz"}}}}`, server.URL)
+		case "/@company/private/-/private-1.0.0.tgz":
+			w.Write(tarball)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	workdir, err := ioutil.TempDir("", "registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(workdir)
+	if err := fileutil.WriteStringToFile(filepath.Join(workdir, "package.json"), `{"name":"app","version":"1.0.0","dependencies":{"@company/private":"1.0.0"}}`); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := NPMRegistry{
+		Scopes:    []scopedRegistry{{Scope: "@company", URL: server.URL}},
+		AuthToken: token,
+	}
+	cleanup := &cleanupStack{}
+	if err := writeRegistryConfig(workdir, registry, false, nil, cleanup); err != nil {
+		t.Fatalf("writeRegistryConfig() error = %v", err)
+	}
+	defer cleanup.run()
+
+	expo := Expo{Workdir: workdir, Registry: registry}
+	cmd := NewCommand("npm", "install", "--no-audit", "--no-fund")
+	cmd.Dir = workdir
+	cmd.Envs = append(expo.envs(), "npm_config_cache="+filepath.Join(workdir, ".npm-cache"))
+
+	out, err := newDefaultCommandRunner(registry.AuthToken).CombinedOutput(context.Background(), cmd)
+	if err != nil {
+		t.Fatalf("npm install error = %v, output: %s", err, out)
+	}
+	if strings.Contains(out, token) {
+		t.Errorf("npm install output contains the token: %s", out)
+	}
+	if exist, err := pathutil.IsPathExists(filepath.Join(workdir, "node_modules", "@company", "private", "package.json")); err != nil || !exist {
+		t.Errorf("installed package exists = %v (%v), want true", exist, err)
+	}
+}
+
+// packageTarball returns an npm package tarball with a package.json.
+func packageTarball(t *testing.T, name, version string) []byte {
+	manifest := []byte(fmt.Sprintf(`{"name":%q,"version":%q}`, name, version))
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: "package/package.json", Mode: 0644, Size: int64(len(manifest))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(manifest); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
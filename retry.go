@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"time"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+const maxRetryDelay = time.Minute
+
+// transientFailure is an output signature of a failure which may succeed if retried.
+type transientFailure struct {
+	reason  string
+	pattern *regexp.Regexp
+}
+
+var transientFailures = []transientFailure{
+	{"connection timed out", regexp.MustCompile(`\bE?TIMEDOUT\b|ESOCKETTIMEDOUT|(?i)network timeout`)},
+	{"connection reset", regexp.MustCompile(`\bECONNRESET\b|(?i)socket hang up`)},
+	{"DNS lookup failed", regexp.MustCompile(`\bEAI_AGAIN\b`)},
+	{"registry server error", regexp.MustCompile(`\bE5\d\d\b|(?i)\b(?:500 Internal Server Error|502 Bad Gateway|503 Service Unavailable|504 Gateway Time-?out)\b|(?i)(?:status|response)(?: code)?:? 5\d\d\b`)},
+	{"rate limited", regexp.MustCompile(`\bE429\b|(?i)\b429 Too Many Requests\b|(?i)too many requests|(?i)rate limit`)},
+}
+
+// transientFailureReason returns the reason of the failure if the output matches a transient failure signature.
+func transientFailureReason(out string) (string, bool) {
+	for _, failure := range transientFailures {
+		if failure.pattern.MatchString(out) {
+			return failure.reason, true
+		}
+	}
+	return "", false
+}
+
+// RetryPolicy retries the network bound commands which failed with a transient error,
+// with exponentially growing, jittered delays between the attempts.
+type RetryPolicy struct {
+	// Attempts is the maximum number of attempts, the command is run once if it is less than 2.
+	Attempts int
+	// Delay is the delay before the first retry, it is doubled for every further retry.
+	Delay time.Duration
+
+	sleep func(time.Duration)
+}
+
+// delay returns the delay before the given retry (1 for the first retry): a random duration
+// between the half and the whole of the exponential backoff, capped at maxRetryDelay.
+func (p RetryPolicy) delay(retry int) time.Duration {
+	backoff := p.Delay
+	for i := 1; i < retry && backoff < maxRetryDelay; i++ {
+		backoff *= 2
+	}
+	if backoff > maxRetryDelay {
+		backoff = maxRetryDelay
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// Do runs fn, which returns the output of a command, and retries it while it fails with a transient error.
+// Killed commands are not retried, and the retries stop when the context is done.
+func (p RetryPolicy) Do(ctx context.Context, fn func() (string, error)) (string, error) {
+	sleep := p.sleep
+	if sleep == nil {
+		sleep = func(d time.Duration) {
+			select {
+			case <-time.After(d):
+			case <-ctx.Done():
+			}
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		out, err := fn()
+		if err == nil || attempt >= p.Attempts || ctx.Err() != nil {
+			return out, err
+		}
+		if _, killed := err.(*CommandKilledError); killed {
+			return out, err
+		}
+
+		reason, transient := transientFailureReason(out)
+		if !transient {
+			return out, err
+		}
+
+		delay := p.delay(attempt)
+		log.Warnf("Attempt %d/%d failed (%s), retrying in %s", attempt, p.Attempts, reason, delay.Round(time.Second))
+		sleep(delay)
+		if ctx.Err() != nil {
+			return out, err
+		}
+		fmt.Println()
+		log.Printf("Attempt %d/%d", attempt+1, p.Attempts)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTransientFailureReason(t *testing.T) {
+	tests := []struct {
+		out        string
+		wantReason string
+	}{
+		{"npm ERR! code ETIMEDOUT\nnpm ERR! errno ETIMEDOUT", "connection timed out"},
+		{"npm ERR! network read ECONNRESET", "connection reset"},
+		{"npm ERR! code E503\nnpm ERR! 503 Service Unavailable - GET https://registry.npmjs.org/expo-cli", "registry server error"},
+		{"error An unexpected error occurred: \"https://registry.yarnpkg.com/react: Request failed \\\"502 Bad Gateway\\\"\".", "registry server error"},
+		{"ApiV2Error: Too many requests, please try again later", "rate limited"},
+		{"npm ERR! code E404\nnpm ERR! 404 Not Found - GET https://registry.npmjs.org/expo-clii", ""},
+		{"Invalid username or password", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.out, func(t *testing.T) {
+			reason, transient := transientFailureReason(tt.out)
+			if reason != tt.wantReason || transient != (tt.wantReason != "") {
+				t.Errorf("transientFailureReason() = %q, %v, want %q", reason, transient, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	tests := []struct {
+		name         string
+		attempts     int
+		outputs      []string
+		wantAttempts int
+		wantErr      bool
+	}{
+		{
+			name:         "transient failure retried until success",
+			attempts:     3,
+			outputs:      []string{"ECONNRESET", "ETIMEDOUT", ""},
+			wantAttempts: 3,
+		},
+		{
+			name:         "permanent failure not retried",
+			attempts:     3,
+			outputs:      []string{"npm ERR! code E404", ""},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "attempts exhausted",
+			attempts:     2,
+			outputs:      []string{"ECONNRESET", "ECONNRESET", ""},
+			wantAttempts: 2,
+			wantErr:      true,
+		},
+		{
+			name:         "zero value policy runs once",
+			outputs:      []string{"ECONNRESET", ""},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var delays []time.Duration
+			policy := RetryPolicy{
+				Attempts: tt.attempts,
+				Delay:    4 * time.Second,
+				sleep:    func(d time.Duration) { delays = append(delays, d) },
+			}
+
+			attempts := 0
+			_, err := policy.Do(context.Background(), func() (string, error) {
+				out := tt.outputs[attempts]
+				attempts++
+				if out == "" {
+					return "", nil
+				}
+				return out, errors.New("exit status 1")
+			})
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Do() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			if len(delays) != tt.wantAttempts-1 {
+				t.Errorf("delays = %v, want %d", delays, tt.wantAttempts-1)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{Delay: 4 * time.Second}
+
+	backoffs := []time.Duration{4, 8, 16, 32, 60, 60}
+	for i, backoff := range backoffs {
+		retry, backoff := i+1, backoff*time.Second
+		// The jitter keeps the delay between the half and the whole backoff.
+		if d := policy.delay(retry); d < backoff/2 || d > backoff {
+			t.Errorf("delay(%d) = %s, want between %s and %s", retry, d, backoff/2, backoff)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-tools/go-steputils/stepconf"
+)
+
+// commandTailLines is the number of last output lines reported if a command is killed.
+const commandTailLines = 20
+
+// Command is a subprocess run by the Step.
+type Command struct {
+	Name string
+	Args []string
+	Dir  string
+	// Envs are appended to the Step's environment.
+	Envs []string
+}
+
+// NewCommand ...
+func NewCommand(name string, args ...string) Command {
+	return Command{Name: name, Args: args}
+}
+
+// String returns the quoted command line.
+func (c Command) String() string {
+	return command.PrintableCommandArgs(false, append([]string{c.Name}, c.Args...))
+}
+
+// CommandRunner runs the Step's subprocesses.
+// If the context is done before the command exits, the command is killed and a *CommandKilledError is returned.
+type CommandRunner interface {
+	// Run runs the command, streaming its output to the Step's output.
+	Run(ctx context.Context, cmd Command) error
+	// RunAndCapture runs the command, streaming its output to the Step's output, and returns the trimmed stdout and stderr.
+	RunAndCapture(ctx context.Context, cmd Command) (string, error)
+	// CombinedOutput runs the command and returns its trimmed stdout and stderr.
+	CombinedOutput(ctx context.Context, cmd Command) (string, error)
+	// Output runs a read-only query command and returns its trimmed stdout.
+	Output(ctx context.Context, cmd Command) (string, error)
+}
+
+// CommandKilledError is returned if the command was killed because its context was done.
+type CommandKilledError struct {
+	// Cmd is the killed command, with the secrets redacted.
+	Cmd Command
+	// Err is the context's error.
+	Err error
+	// LastLines are the last lines of the command's output.
+	LastLines []string
+}
+
+// Error ...
+func (e *CommandKilledError) Error() string {
+	return fmt.Sprintf("%s was killed: %s", e.Cmd, e.Err)
+}
+
+// defaultCommandRunner runs the commands as subprocesses, the secrets are masked in their output.
+type defaultCommandRunner struct {
+	secrets []string
+}
+
+func newDefaultCommandRunner(secrets ...stepconf.Secret) defaultCommandRunner {
+	return defaultCommandRunner{secrets: secretValues(secrets...)}
+}
+
+// redact returns a copy of the command with the secrets masked in its name and arguments.
+func (r defaultCommandRunner) redact(cmd Command) Command {
+	redacted := cmd
+	redacted.Name = redactSecrets(cmd.Name, r.secrets)
+	redacted.Args = make([]string, len(cmd.Args))
+	for i, arg := range cmd.Args {
+		redacted.Args[i] = redactSecrets(arg, r.secrets)
+	}
+	return redacted
+}
+
+func (defaultCommandRunner) model(cmd Command) *command.Model {
+	model := command.New(cmd.Name, cmd.Args...)
+	if cmd.Dir != "" {
+		model.SetDir(cmd.Dir)
+	}
+	if len(cmd.Envs) > 0 {
+		model.AppendEnvs(cmd.Envs...)
+	}
+	return model
+}
+
+// run runs the command in its own process group, and writes its redacted stdout and stderr to the writers.
+// The process group is killed if the context is done, as the Expo CLI and the package managers start child processes.
+func (r defaultCommandRunner) run(ctx context.Context, cmd Command, stdout, stderr io.Writer) error {
+	tail := newTailWriter(commandTailLines)
+	outWriter := newRedactWriter(io.MultiWriter(stdout, tail), r.secrets)
+	errWriter := newRedactWriter(io.MultiWriter(stderr, tail), r.secrets)
+
+	c := r.model(cmd).GetCmd()
+	c.Stdout = outWriter
+	c.Stderr = errWriter
+	c.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	if err := c.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Wait()
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		if killErr := syscall.Kill(-c.Process.Pid, syscall.SIGKILL); killErr != nil {
+			log.Warnf("Failed to kill the process group of %s: %s", r.redact(cmd), killErr)
+		}
+		<-done
+		err = ctx.Err()
+	}
+
+	closeRedactWriters(outWriter, errWriter)
+	if ctx.Err() != nil && err == ctx.Err() {
+		return &CommandKilledError{Cmd: r.redact(cmd), Err: err, LastLines: tail.lines()}
+	}
+	return err
+}
+
+// Run ...
+func (r defaultCommandRunner) Run(ctx context.Context, cmd Command) error {
+	return r.run(ctx, cmd, os.Stdout, os.Stderr)
+}
+
+// RunAndCapture ...
+func (r defaultCommandRunner) RunAndCapture(ctx context.Context, cmd Command) (string, error) {
+	out := &syncBuffer{}
+	err := r.run(ctx, cmd, io.MultiWriter(os.Stdout, out), io.MultiWriter(os.Stderr, out))
+	return strings.TrimSpace(out.String()), err
+}
+
+// CombinedOutput ...
+func (r defaultCommandRunner) CombinedOutput(ctx context.Context, cmd Command) (string, error) {
+	out := &syncBuffer{}
+	err := r.run(ctx, cmd, out, out)
+	return strings.TrimSpace(out.String()), err
+}
+
+// Output ...
+func (r defaultCommandRunner) Output(ctx context.Context, cmd Command) (string, error) {
+	out := &syncBuffer{}
+	err := r.run(ctx, cmd, out, ioutil.Discard)
+	return strings.TrimSpace(out.String()), err
+}
+
+func closeRedactWriters(writers ...*redactWriter) {
+	for _, w := range writers {
+		if err := w.Close(); err != nil {
+			log.Warnf("Failed to write command output: %s", err)
+		}
+	}
+}
+
+// syncBuffer is a buffer safe for the concurrent writes of a command's stdout and stderr.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// Write ...
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// String ...
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// tailWriter keeps the last lines written to it.
+type tailWriter struct {
+	mu      sync.Mutex
+	max     int
+	tail    []string
+	partial string
+}
+
+func newTailWriter(max int) *tailWriter {
+	return &tailWriter{max: max}
+}
+
+// Write ...
+func (w *tailWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	lines := strings.Split(w.partial+string(p), "\n")
+	w.partial = lines[len(lines)-1]
+	w.tail = append(w.tail, lines[:len(lines)-1]...)
+	if len(w.tail) > w.max {
+		w.tail = w.tail[len(w.tail)-w.max:]
+	}
+	return len(p), nil
+}
+
+func (w *tailWriter) lines() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	lines := append([]string{}, w.tail...)
+	if w.partial != "" {
+		lines = append(lines, w.partial)
+	}
+	if len(lines) > w.max {
+		lines = lines[len(lines)-w.max:]
+	}
+	return lines
+}
+
+// dryRunCommandRunner records the commands in the plan instead of running them,
+// only the read-only queries are run.
+type dryRunCommandRunner struct {
+	plan   *Plan
+	runner CommandRunner
+}
+
+// Run ...
+func (r dryRunCommandRunner) Run(ctx context.Context, cmd Command) error {
+	r.plan.AddCommand(cmd)
+	return nil
+}
+
+// RunAndCapture ...
+func (r dryRunCommandRunner) RunAndCapture(ctx context.Context, cmd Command) (string, error) {
+	r.plan.AddCommand(cmd)
+	return "", nil
+}
+
+// CombinedOutput ...
+func (r dryRunCommandRunner) CombinedOutput(ctx context.Context, cmd Command) (string, error) {
+	r.plan.AddCommand(cmd)
+	return "", nil
+}
+
+// Output ...
+func (r dryRunCommandRunner) Output(ctx context.Context, cmd Command) (string, error) {
+	return r.runner.Output(ctx, cmd)
+}
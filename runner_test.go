@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"strings"
+)
+
+// fakeCommandRunner records the commands instead of running them.
+type fakeCommandRunner struct {
+	commands []Command
+	// errors maps a command line prefix (like `expo eject`) to the error returned for it.
+	errors map[string]error
+	// outputs maps a command line prefix to the output returned for it.
+	outputs map[string]string
+	// hooks maps a command line prefix to a function simulating the command's side effects.
+	hooks map[string]func(cmd Command)
+}
+
+func newFakeCommandRunner() *fakeCommandRunner {
+	return &fakeCommandRunner{
+		errors:  map[string]error{},
+		outputs: map[string]string{},
+		hooks:   map[string]func(cmd Command){},
+	}
+}
+
+func (r *fakeCommandRunner) line(cmd Command) string {
+	return strings.Join(append([]string{cmd.Name}, cmd.Args...), " ")
+}
+
+func (r *fakeCommandRunner) result(cmd Command) (string, error) {
+	r.commands = append(r.commands, cmd)
+
+	line := r.line(cmd)
+	for prefix, hook := range r.hooks {
+		if strings.HasPrefix(line, prefix) {
+			hook(cmd)
+		}
+	}
+
+	var out string
+	for prefix, o := range r.outputs {
+		if strings.HasPrefix(line, prefix) {
+			out = o
+		}
+	}
+	for prefix, err := range r.errors {
+		if strings.HasPrefix(line, prefix) {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+func (r *fakeCommandRunner) Run(ctx context.Context, cmd Command) error {
+	_, err := r.result(cmd)
+	return err
+}
+
+func (r *fakeCommandRunner) RunAndCapture(ctx context.Context, cmd Command) (string, error) {
+	return r.result(cmd)
+}
+
+func (r *fakeCommandRunner) CombinedOutput(ctx context.Context, cmd Command) (string, error) {
+	return r.result(cmd)
+}
+
+func (r *fakeCommandRunner) Output(ctx context.Context, cmd Command) (string, error) {
+	return r.result(cmd)
+}
+
+// lines returns the recorded command lines.
+func (r *fakeCommandRunner) lines() []string {
+	var lines []string
+	for _, cmd := range r.commands {
+		lines = append(lines, r.line(cmd))
+	}
+	return lines
+}
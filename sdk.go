@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// prebuildMinSDKVersion is the first Expo SDK which ships the project-local @expo/cli
+// and no longer supports the classic `expo eject`.
+const prebuildMinSDKVersion = 46
+
+var sdkMajorVersionRegexp = regexp.MustCompile(`(\d+)(\.|$)`)
+
+// parseSDKMajorVersion returns the major version of an expo dependency version spec (like ~39.0.2 or ^49.0.0).
+func parseSDKMajorVersion(spec string) (int, error) {
+	match := sdkMajorVersionRegexp.FindStringSubmatch(spec)
+	if match == nil {
+		return 0, fmt.Errorf("no version number found in: %s", spec)
+	}
+	return strconv.Atoi(match[1])
+}
+
+// sdkVersions are the package versions matching an Expo SDK version.
+type sdkVersions struct {
+	ReactNative string
+	React       string
+	// ExpoCLI is the classic expo-cli version to install, empty if the SDK ships @expo/cli.
+	ExpoCLI string
+}
+
+// sdkCompatibility maps the Expo SDK major versions to the matching package versions.
+var sdkCompatibility = map[int]sdkVersions{
+	38: {ReactNative: "0.62.2", React: "16.11.0", ExpoCLI: "3.28.6"},
+	39: {ReactNative: "0.63.2", React: "16.13.1", ExpoCLI: "3.28.6"},
+	40: {ReactNative: "0.63.2", React: "16.13.1", ExpoCLI: "4.1.6"},
+	41: {ReactNative: "0.63.2", React: "16.13.1", ExpoCLI: "4.4.8"},
+	42: {ReactNative: "0.63.2", React: "16.13.1", ExpoCLI: "4.13.0"},
+	43: {ReactNative: "0.64.3", React: "17.0.1", ExpoCLI: "5.0.3"},
+	44: {ReactNative: "0.64.3", React: "17.0.1", ExpoCLI: "5.3.2"},
+	45: {ReactNative: "0.68.2", React: "17.0.2", ExpoCLI: "5.5.1"},
+	46: {ReactNative: "0.69.9", React: "18.0.0"},
+	47: {ReactNative: "0.70.8", React: "18.1.0"},
+	48: {ReactNative: "0.71.14", React: "18.2.0"},
+	49: {ReactNative: "0.72.10", React: "18.2.0"},
+	50: {ReactNative: "0.73.6", React: "18.2.0"},
+	51: {ReactNative: "0.74.5", React: "18.2.0"},
+	52: {ReactNative: "0.76.9", React: "18.3.1"},
+	53: {ReactNative: "0.79.6", React: "19.0.0"},
+	54: {ReactNative: "0.81.4", React: "19.1.0"},
+}
+
+// ejectModeForSDK picks prebuild for SDKs shipping @expo/cli and falls back to the classic eject otherwise,
+// including if the SDK version is unknown (0).
+func ejectModeForSDK(sdkVersion int) EjectMode {
+	if sdkVersion >= prebuildMinSDKVersion {
+		return EjectModePrebuild
+	}
+	return EjectModeEject
+}
+
+// alignedDependencyOverrides returns the react-native and react versions matching the SDK version.
+func alignedDependencyOverrides(sdkVersion int) ([]dependencyOverride, error) {
+	versions, ok := sdkCompatibility[sdkVersion]
+	if !ok {
+		return nil, fmt.Errorf("Expo SDK %d is not in the compatibility table", sdkVersion)
+	}
+
+	return []dependencyOverride{
+		{Section: dependenciesSection, Name: "react-native", Version: versions.ReactNative},
+		{Section: dependenciesSection, Name: "react", Version: versions.React},
+	}, nil
+}
+
+var majorMinorVersionRegexp = regexp.MustCompile(`^[\^~=v]*(\d+)\.(\d+)`)
+
+// warnSDKDivergence warns for each react-native and react dependency not matching the compatibility table.
+func warnSDKDivergence(workdir string, sdkVersion int) {
+	versions, ok := sdkCompatibility[sdkVersion]
+	if !ok {
+		log.Warnf("Expo SDK %d is not in the compatibility table, can not check the react-native and react versions", sdkVersion)
+		return
+	}
+
+	packages, err := parsePackageJSON(filepath.Join(workdir, "package.json"))
+	if err != nil {
+		log.Warnf("%s", err)
+		return
+	}
+	deps, err := packages.Object("dependencies")
+	if err != nil {
+		log.Warnf("Failed to parse dependencies from package.json file: %s", err)
+		return
+	}
+
+	for _, dep := range []struct {
+		name     string
+		expected string
+	}{
+		{"react-native", versions.ReactNative},
+		{"react", versions.React},
+	} {
+		spec, err := deps.String(dep.name)
+		if err != nil {
+			continue
+		}
+
+		// Only versions are compared, not tarball URLs or tags.
+		actual := majorMinorVersionRegexp.FindStringSubmatch(spec)
+		expected := majorMinorVersionRegexp.FindStringSubmatch(dep.expected)
+		if actual == nil || expected == nil {
+			continue
+		}
+		if actual[1] != expected[1] || actual[2] != expected[2] {
+			log.Warnf("%s version (%s) diverges from the version matching Expo SDK %d (%s)", dep.name, spec, sdkVersion, dep.expected)
+		}
+	}
+}
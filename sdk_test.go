@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+func TestParseSDKMajorVersion(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    int
+		wantErr bool
+	}{
+		{spec: "~39.0.2", want: 39},
+		{spec: "^49.0.0", want: 49},
+		{spec: "46.0.0-beta.1", want: 46},
+		{spec: ">=45.0.0 <46", want: 45},
+		{spec: "50", want: 50},
+		{spec: "latest", wantErr: true},
+		{spec: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := parseSDKMajorVersion(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSDKMajorVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseSDKMajorVersion() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEjectModeForSDK(t *testing.T) {
+	tests := []struct {
+		sdkVersion int
+		want       EjectMode
+	}{
+		{sdkVersion: 0, want: EjectModeEject},
+		{sdkVersion: 39, want: EjectModeEject},
+		{sdkVersion: 45, want: EjectModeEject},
+		{sdkVersion: 46, want: EjectModePrebuild},
+		{sdkVersion: 54, want: EjectModePrebuild},
+	}
+	for _, tt := range tests {
+		if got := ejectModeForSDK(tt.sdkVersion); got != tt.want {
+			t.Errorf("ejectModeForSDK(%d) = %s, want %s", tt.sdkVersion, got, tt.want)
+		}
+	}
+}
+
+func TestSDKCompatibility_ExpoCLI(t *testing.T) {
+	// The classic expo-cli is installed only for the SDKs generated with expo eject.
+	for sdkVersion, versions := range sdkCompatibility {
+		if eject := ejectModeForSDK(sdkVersion) == EjectModeEject; eject != (versions.ExpoCLI != "") {
+			t.Errorf("SDK %d: expo-cli version %q does not match the %s mode", sdkVersion, versions.ExpoCLI, ejectModeForSDK(sdkVersion))
+		}
+	}
+}
+
+func TestAlignedDependencyOverrides(t *testing.T) {
+	got, err := alignedDependencyOverrides(45)
+	if err != nil {
+		t.Fatalf("alignedDependencyOverrides() error = %v", err)
+	}
+	want := []dependencyOverride{
+		{Section: dependenciesSection, Name: "react-native", Version: "0.68.2"},
+		{Section: dependenciesSection, Name: "react", Version: "17.0.2"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("alignedDependencyOverrides() = %+v, want %+v", got, want)
+	}
+
+	if _, err := alignedDependencyOverrides(20); err == nil {
+		t.Error("alignedDependencyOverrides() succeeded for an SDK missing from the table")
+	}
+}
+
+func TestWarnSDKDivergence(t *testing.T) {
+	tests := []struct {
+		name         string
+		sdkVersion   int
+		dependencies string
+		want         []string
+	}{
+		{
+			name:         "matching versions",
+			sdkVersion:   39,
+			dependencies: `{"react-native": "0.63.4", "react": "~16.13.0"}`,
+		},
+		{
+			name:         "diverging react-native",
+			sdkVersion:   39,
+			dependencies: `{"react-native": "^0.61.0", "react": "16.13.1"}`,
+			want:         []string{"react-native version (^0.61.0) diverges from the version matching Expo SDK 39 (0.63.2)"},
+		},
+		{
+			name:         "tarball URLs are not compared",
+			sdkVersion:   39,
+			dependencies: `{"react-native": "https://github.com/expo/react-native/archive/sdk-39.0.0.tar.gz"}`,
+		},
+		{
+			name:         "unknown SDK",
+			sdkVersion:   20,
+			dependencies: `{"react-native": "0.50.0"}`,
+			want:         []string{"Expo SDK 20 is not in the compatibility table"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workdir := createProject(t, map[string]string{"package.json": `{"dependencies": ` + tt.dependencies + `}`})
+
+			var out bytes.Buffer
+			log.SetOutWriter(&out)
+			defer log.SetOutWriter(os.Stdout)
+
+			warnSDKDivergence(workdir, tt.sdkVersion)
+
+			var got []string
+			for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+				if line != "" {
+					got = append(got, line)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("warnings = %q, want %q", got, tt.want)
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(got[i], want) {
+					t.Errorf("warning = %q, want %q", got[i], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// semanticVersion is a major.minor.patch version with an optional prerelease tag, build metadata is dropped.
+type semanticVersion struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+}
+
+// String ...
+func (v semanticVersion) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	return s
+}
+
+var partialVersionPattern = regexp.MustCompile(`^v?(\d+|[xX*])(?:\.(\d+|[xX*]))?(?:\.(\d+|[xX*]))?(?:-([0-9A-Za-z.-]+))?(?:\+[0-9A-Za-z.-]+)?$`)
+
+// partialVersion is a version with possibly missing or wildcard (x, *) components, -1 marks a missing component.
+type partialVersion struct {
+	parts      [3]int
+	prerelease string
+}
+
+func parsePartialVersion(s string) (partialVersion, error) {
+	match := partialVersionPattern.FindStringSubmatch(s)
+	if match == nil {
+		return partialVersion{}, fmt.Errorf("invalid version: %s", s)
+	}
+
+	v := partialVersion{parts: [3]int{-1, -1, -1}, prerelease: match[4]}
+	for i, part := range match[1:4] {
+		if part == "" || part == "x" || part == "X" || part == "*" {
+			break
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return partialVersion{}, fmt.Errorf("invalid version: %s", s)
+		}
+		v.parts[i] = n
+	}
+	return v, nil
+}
+
+// complete returns the version with the missing components set to 0.
+func (v partialVersion) complete() semanticVersion {
+	version := semanticVersion{Prerelease: v.prerelease}
+	for i, n := range v.parts {
+		if n < 0 {
+			n = 0
+		}
+		switch i {
+		case 0:
+			version.Major = n
+		case 1:
+			version.Minor = n
+		case 2:
+			version.Patch = n
+		}
+	}
+	return version
+}
+
+// specified returns the number of leading specified components.
+func (v partialVersion) specified() int {
+	for i, n := range v.parts {
+		if n < 0 {
+			return i
+		}
+	}
+	return len(v.parts)
+}
+
+// next returns the smallest version above every version matching the first n components.
+func (v partialVersion) next(n int) semanticVersion {
+	version := v.complete()
+	version.Prerelease = ""
+	switch n {
+	case 1:
+		return semanticVersion{Major: version.Major + 1}
+	case 2:
+		return semanticVersion{Major: version.Major, Minor: version.Minor + 1}
+	default:
+		return semanticVersion{Major: version.Major, Minor: version.Minor, Patch: version.Patch + 1}
+	}
+}
+
+func parseSemanticVersion(s string) (semanticVersion, error) {
+	v, err := parsePartialVersion(strings.TrimPrefix(strings.TrimSpace(s), "="))
+	if err != nil {
+		return semanticVersion{}, err
+	}
+	if v.specified() != 3 {
+		return semanticVersion{}, fmt.Errorf("invalid version: %s, expected major.minor.patch", s)
+	}
+	return v.complete(), nil
+}
+
+// versionComparator is a single comparison like >=1.2.3.
+type versionComparator struct {
+	op      string
+	version semanticVersion
+}
+
+// versionRange is an npm style version range: any of the comparator sets has to match, all comparators of a set.
+type versionRange [][]versionComparator
+
+var rangeOperatorPattern = regexp.MustCompile(`(>=|<=|>|<|=|\^|~>?)\s+`)
+
+// parseVersionRange parses an npm style range, like ^1.2.3, ~0.63.0, >=14 <17, 1.x || 2.x or 1.2.0 - 1.4.0.
+func parseVersionRange(spec string) (versionRange, error) {
+	var r versionRange
+	for _, set := range strings.Split(spec, "||") {
+		set = strings.TrimSpace(set)
+
+		if parts := strings.Split(set, " - "); len(parts) == 2 {
+			comparators, err := parseHyphenRange(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+			if err != nil {
+				return nil, err
+			}
+			r = append(r, comparators)
+			continue
+		}
+
+		// Operators may be separated from the version by whitespace.
+		set = rangeOperatorPattern.ReplaceAllString(set, "$1")
+
+		comparators := []versionComparator{}
+		for _, token := range strings.Fields(set) {
+			parsed, err := parseRangeToken(token)
+			if err != nil {
+				return nil, err
+			}
+			comparators = append(comparators, parsed...)
+		}
+		r = append(r, comparators)
+	}
+	return r, nil
+}
+
+func parseHyphenRange(from, to string) ([]versionComparator, error) {
+	lower, err := parsePartialVersion(from)
+	if err != nil {
+		return nil, err
+	}
+	upper, err := parsePartialVersion(to)
+	if err != nil {
+		return nil, err
+	}
+
+	comparators := []versionComparator{{">=", lower.complete()}}
+	if n := upper.specified(); n == 3 {
+		comparators = append(comparators, versionComparator{"<=", upper.complete()})
+	} else if n > 0 {
+		comparators = append(comparators, versionComparator{"<", upper.next(n)})
+	}
+	return comparators, nil
+}
+
+// parseRangeToken desugars a single range token into primitive comparators.
+func parseRangeToken(token string) ([]versionComparator, error) {
+	op := ""
+	for _, candidate := range []string{">=", "<=", "~>", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(token, candidate) {
+			op = candidate
+			break
+		}
+	}
+
+	v, err := parsePartialVersion(strings.TrimPrefix(token, op))
+	if err != nil {
+		return nil, err
+	}
+	n := v.specified()
+	lower := v.complete()
+
+	switch op {
+	case "", "=":
+		if n == 0 {
+			return nil, nil
+		}
+		if n == 3 {
+			return []versionComparator{{"=", lower}}, nil
+		}
+		return []versionComparator{{">=", lower}, {"<", v.next(n)}}, nil
+	case "^":
+		// Allows changes which do not modify the left-most non-zero component.
+		switch {
+		case n == 0:
+			return nil, nil
+		case lower.Major > 0 || n == 1:
+			return []versionComparator{{">=", lower}, {"<", v.next(1)}}, nil
+		case lower.Minor > 0 || n == 2:
+			return []versionComparator{{">=", lower}, {"<", v.next(2)}}, nil
+		default:
+			return []versionComparator{{">=", lower}, {"<", v.next(3)}}, nil
+		}
+	case "~", "~>":
+		if n == 0 {
+			return nil, nil
+		}
+		if n == 1 {
+			return []versionComparator{{">=", lower}, {"<", v.next(1)}}, nil
+		}
+		return []versionComparator{{">=", lower}, {"<", v.next(2)}}, nil
+	case ">":
+		if n == 0 {
+			return []versionComparator{{"<", semanticVersion{}}}, nil
+		}
+		if n < 3 {
+			return []versionComparator{{">=", v.next(n)}}, nil
+		}
+		return []versionComparator{{">", lower}}, nil
+	case "<=":
+		if n > 0 && n < 3 {
+			return []versionComparator{{"<", v.next(n)}}, nil
+		}
+		return []versionComparator{{"<=", lower}}, nil
+	default:
+		return []versionComparator{{op, lower}}, nil
+	}
+}
+
+var nonRegistryVersionPrefixes = []string{"http://", "https://", "git://", "git+", "github:", "gitlab:", "bitbucket:", "file:", "link:", "npm:"}
+
+// isVersionSpec reports whether the dependency version is an npm semver version or range,
+// or a non-registry spec like a tarball URL, a git URL or a local path.
+func isVersionSpec(spec string) bool {
+	spec = strings.TrimSpace(spec)
+	for _, prefix := range nonRegistryVersionPrefixes {
+		if strings.HasPrefix(spec, prefix) {
+			return true
+		}
+	}
+	_, err := parseVersionRange(spec)
+	return err == nil
+}
+
+// compareSemanticVersions returns -1, 0 or 1 if a is lower, equal or higher than b.
+// A prerelease is lower than its release, prereleases are compared as strings.
+func compareSemanticVersions(a, b semanticVersion) int {
+	for _, d := range []int{a.Major - b.Major, a.Minor - b.Minor, a.Patch - b.Patch} {
+		if d < 0 {
+			return -1
+		} else if d > 0 {
+			return 1
+		}
+	}
+
+	switch {
+	case a.Prerelease == b.Prerelease:
+		return 0
+	case a.Prerelease == "":
+		return 1
+	case b.Prerelease == "":
+		return -1
+	case a.Prerelease < b.Prerelease:
+		return -1
+	default:
+		return 1
+	}
+}
+
+func (c versionComparator) matches(v semanticVersion) bool {
+	cmp := compareSemanticVersions(v, c.version)
+	switch c.op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	default:
+		return cmp == 0
+	}
+}
+
+// Contains reports whether the version satisfies the range.
+func (r versionRange) Contains(v semanticVersion) bool {
+	for _, set := range r {
+		matches := true
+		for _, c := range set {
+			if !c.matches(v) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
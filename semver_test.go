@@ -0,0 +1,73 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseVersionRange(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    versionRange
+		wantErr bool
+	}{
+		{spec: "0.63.2", want: versionRange{{{"=", semanticVersion{0, 63, 2, ""}}}}},
+		{spec: "~0.63.2", want: versionRange{{{">=", semanticVersion{0, 63, 2, ""}}, {"<", semanticVersion{0, 64, 0, ""}}}}},
+		{spec: "^0.0.3", want: versionRange{{{">=", semanticVersion{0, 0, 3, ""}}, {"<", semanticVersion{0, 0, 4, ""}}}}},
+		{spec: ">= 14 <17", want: versionRange{{{">=", semanticVersion{14, 0, 0, ""}}, {"<", semanticVersion{17, 0, 0, ""}}}}},
+		{spec: "1.x || >2.1", want: versionRange{
+			{{">=", semanticVersion{1, 0, 0, ""}}, {"<", semanticVersion{2, 0, 0, ""}}},
+			{{">=", semanticVersion{2, 2, 0, ""}}},
+		}},
+		{spec: "1.2 - 1.4.0", want: versionRange{{{">=", semanticVersion{1, 2, 0, ""}}, {"<=", semanticVersion{1, 4, 0, ""}}}}},
+		{spec: "*", want: versionRange{{}}},
+		{spec: "latest", wantErr: true},
+		{spec: "^1.2.3.4", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := parseVersionRange(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseVersionRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseVersionRange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersionRangeContains(t *testing.T) {
+	tests := []struct {
+		spec    string
+		version string
+		want    bool
+	}{
+		{">=14", "14.0.0", true},
+		{">=14", "12.22.12", false},
+		{">=18.18", "18.17.1", false},
+		{"^16.13.0 || >=18", "16.20.2", true},
+		{"^16.13.0 || >=18", "17.9.1", false},
+		{"14.x - 16", "16.9.0", true},
+		{"14.x - 16", "17.0.0", false},
+		{"~6.14.0", "6.14.16", true},
+		{"<7", "7.0.0-rc.1", true},
+		{">=7.0.0-rc.1", "7.0.0", true},
+		{"*", "0.0.1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec+" "+tt.version, func(t *testing.T) {
+			r, err := parseVersionRange(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			v, err := parseSemanticVersion(tt.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := r.Contains(v); got != tt.want {
+				t.Errorf("Contains() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-io/go-utils/pathutil"
+)
+
+const (
+	androidUpdateURLKey  = "expo.modules.updates.EXPO_UPDATE_URL"
+	iosUpdateURLKey      = "EXUpdatesURL"
+	iosBundleIDKey       = "CFBundleIdentifier"
+	expoUpdatesPackage   = "expo-updates"
+	productBundleIDMacro = "$(PRODUCT_BUNDLE_IDENTIFIER)"
+)
+
+var (
+	applicationIDRegexp   = regexp.MustCompile(`(?m)^\s*applicationId\s*=?\s*["']([^"']+)["']`)
+	productBundleIDRegexp = regexp.MustCompile(`PRODUCT_BUNDLE_IDENTIFIER = "?([^";]+)"?;`)
+)
+
+// ValidationError lists every problem found in the generated native projects.
+type ValidationError struct {
+	Issues []string
+}
+
+// Error ...
+func (e ValidationError) Error() string {
+	return "generated native projects are invalid:\n- " + strings.Join(e.Issues, "\n- ")
+}
+
+// nativeProjectExpectations are the values the generated native projects have to match.
+type nativeProjectExpectations struct {
+	AndroidPackage   string
+	BundleIdentifier string
+	UpdateURL        string
+	// CheckUpdates is set when expo-updates is installed and enabled, so the update keys have to be present.
+	CheckUpdates bool
+}
+
+type androidManifest struct {
+	Package     string `xml:"package,attr"`
+	Application struct {
+		MetaData []struct {
+			Name  string `xml:"http://schemas.android.com/apk/res/android name,attr"`
+			Value string `xml:"http://schemas.android.com/apk/res/android value,attr"`
+		} `xml:"meta-data"`
+	} `xml:"application"`
+}
+
+// validateNativeProjects checks the selected platforms' generated native projects against the project's Expo config.
+func validateNativeProjects(workdir string, platform Platform, config ExpoConfig) error {
+	expectations, err := readNativeProjectExpectations(workdir, config)
+	if err != nil {
+		return err
+	}
+
+	var issues []string
+	if platform.Android() {
+		issues = append(issues, validateAndroidProject(filepath.Join(workdir, "android"), expectations)...)
+	}
+	if platform.IOS() {
+		issues = append(issues, validateIOSProject(filepath.Join(workdir, "ios"), expectations)...)
+	}
+	if len(issues) > 0 {
+		return ValidationError{Issues: issues}
+	}
+	return nil
+}
+
+func readNativeProjectExpectations(workdir string, config ExpoConfig) (nativeProjectExpectations, error) {
+	expectations := nativeProjectExpectations{
+		AndroidPackage:   config.Android.Package,
+		BundleIdentifier: config.IOS.BundleIdentifier,
+		UpdateURL:        config.Updates.URL,
+	}
+
+	packages, err := parsePackageJSON(filepath.Join(workdir, "package.json"))
+	if err != nil {
+		return nativeProjectExpectations{}, err
+	}
+	deps, err := packages.Object("dependencies")
+	if err != nil {
+		return nativeProjectExpectations{}, fmt.Errorf("Failed to parse dependencies from package.json file: %s", err)
+	}
+	_, hasUpdates := deps[expoUpdatesPackage]
+	expectations.CheckUpdates = hasUpdates && config.UpdatesEnabled()
+
+	return expectations, nil
+}
+
+func validateAndroidProject(androidDir string, expectations nativeProjectExpectations) []string {
+	var issues []string
+
+	manifestPth := filepath.Join(androidDir, defaultAndroidModule, "src", "main", "AndroidManifest.xml")
+	manifest, err := parseAndroidManifest(manifestPth)
+	if err != nil {
+		return append(issues, fmt.Sprintf("%s: %s", manifestPth, err))
+	}
+
+	if expectations.CheckUpdates {
+		found := false
+		for _, metaData := range manifest.Application.MetaData {
+			if metaData.Name != androidUpdateURLKey {
+				continue
+			}
+			found = true
+			if expectations.UpdateURL != "" && metaData.Value != expectations.UpdateURL {
+				issues = append(issues, fmt.Sprintf("%s: %s is %s, expected %s", manifestPth, androidUpdateURLKey, metaData.Value, expectations.UpdateURL))
+			}
+		}
+		if !found {
+			issues = append(issues, fmt.Sprintf("%s: %s meta-data is missing", manifestPth, androidUpdateURLKey))
+		}
+	}
+
+	if expectations.AndroidPackage == "" {
+		return issues
+	}
+
+	buildGradlePth := filepath.Join(androidDir, defaultAndroidModule, androidBuildGradleFile)
+	content, err := fileutil.ReadStringFromFile(buildGradlePth)
+	if err != nil {
+		return append(issues, fmt.Sprintf("%s: %s", buildGradlePth, err))
+	}
+
+	if match := applicationIDRegexp.FindStringSubmatch(content); match != nil {
+		if match[1] != expectations.AndroidPackage {
+			issues = append(issues, fmt.Sprintf("%s: applicationId is %s, expected %s (expo.android.package)", buildGradlePth, match[1], expectations.AndroidPackage))
+		}
+	} else if manifest.Package != expectations.AndroidPackage {
+		issues = append(issues, fmt.Sprintf("%s: package is %s, expected %s (expo.android.package)", manifestPth, manifest.Package, expectations.AndroidPackage))
+	}
+
+	return issues
+}
+
+func validateIOSProject(iosDir string, expectations nativeProjectExpectations) []string {
+	var issues []string
+
+	xcodeProjects, err := filepath.Glob(filepath.Join(iosDir, "*"+xcodeProjectExtension))
+	if err != nil {
+		return append(issues, err.Error())
+	}
+	if len(xcodeProjects) == 0 {
+		return append(issues, fmt.Sprintf("%s: no Xcode project found", iosDir))
+	}
+	xcodeProject := xcodeProjects[0]
+	targetDir := filepath.Join(iosDir, strings.TrimSuffix(filepath.Base(xcodeProject), xcodeProjectExtension))
+
+	if expectations.CheckUpdates {
+		expoPlistPth := filepath.Join(targetDir, "Supporting", "Expo.plist")
+		expoPlist, err := parsePlistStrings(expoPlistPth)
+		if err != nil {
+			issues = append(issues, fmt.Sprintf("%s: %s", expoPlistPth, err))
+		} else if updateURL, ok := expoPlist[iosUpdateURLKey]; !ok {
+			issues = append(issues, fmt.Sprintf("%s: %s key is missing", expoPlistPth, iosUpdateURLKey))
+		} else if expectations.UpdateURL != "" && updateURL != expectations.UpdateURL {
+			issues = append(issues, fmt.Sprintf("%s: %s is %s, expected %s", expoPlistPth, iosUpdateURLKey, updateURL, expectations.UpdateURL))
+		}
+	}
+
+	if expectations.BundleIdentifier == "" {
+		return issues
+	}
+
+	infoPlistPth := filepath.Join(targetDir, "Info.plist")
+	infoPlist, err := parsePlistStrings(infoPlistPth)
+	if err != nil {
+		return append(issues, fmt.Sprintf("%s: %s", infoPlistPth, err))
+	}
+
+	bundleID := infoPlist[iosBundleIDKey]
+	source := infoPlistPth
+	if bundleID == productBundleIDMacro {
+		pbxprojPth := filepath.Join(xcodeProject, "project.pbxproj")
+		content, err := fileutil.ReadStringFromFile(pbxprojPth)
+		if err != nil {
+			return append(issues, fmt.Sprintf("%s: %s", pbxprojPth, err))
+		}
+
+		bundleID = ""
+		source = pbxprojPth
+		if match := productBundleIDRegexp.FindStringSubmatch(content); match != nil {
+			bundleID = match[1]
+		}
+	}
+
+	if bundleID != expectations.BundleIdentifier {
+		issues = append(issues, fmt.Sprintf("%s: bundle identifier is %s, expected %s (expo.ios.bundleIdentifier)", source, bundleID, expectations.BundleIdentifier))
+	}
+
+	return issues
+}
+
+func parseAndroidManifest(pth string) (androidManifest, error) {
+	b, err := fileutil.ReadBytesFromFile(pth)
+	if err != nil {
+		return androidManifest{}, err
+	}
+
+	var manifest androidManifest
+	if err := xml.Unmarshal(b, &manifest); err != nil {
+		return androidManifest{}, fmt.Errorf("failed to parse: %s", err)
+	}
+	return manifest, nil
+}
+
+// parsePlistStrings returns the scalar values of an XML property list's root dictionary,
+// nested dictionaries and arrays are skipped.
+func parsePlistStrings(pth string) (map[string]string, error) {
+	if exist, err := pathutil.IsPathExists(pth); err != nil {
+		return nil, err
+	} else if !exist {
+		return nil, os.ErrNotExist
+	}
+
+	f, err := os.Open(pth)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	values := map[string]string{}
+	decoder := xml.NewDecoder(f)
+	depth := 0
+	key := ""
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return values, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse: %s", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			// plist > dict > key|value
+			if depth != 3 {
+				continue
+			}
+
+			switch t.Name.Local {
+			case "key":
+				if err := decoder.DecodeElement(&key, &t); err != nil {
+					return nil, fmt.Errorf("failed to parse: %s", err)
+				}
+				depth--
+			case "string", "integer", "real", "date":
+				var value string
+				if err := decoder.DecodeElement(&value, &t); err != nil {
+					return nil, fmt.Errorf("failed to parse: %s", err)
+				}
+				values[key] = value
+				depth--
+			case "true", "false":
+				values[key] = t.Name.Local
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/fileutil"
+)
+
+// validateFixtures maps the native project files to the recorded files in testdata/validate.
+var validateFixtures = map[string]string{
+	"android/app/src/main/AndroidManifest.xml": "AndroidManifest.xml",
+	"android/app/build.gradle":                 "build.gradle",
+	"ios/expoapp/Supporting/Expo.plist":        "Expo.plist",
+	"ios/expoapp/Info.plist":                   "Info.plist",
+	"ios/expoapp.xcodeproj/project.pbxproj":    "project.pbxproj",
+}
+
+func createValidateProject(t *testing.T, packageJSON string, fixtures map[string]string) string {
+	files := map[string]string{"package.json": packageJSON}
+	for pth, fixture := range validateFixtures {
+		if override, ok := fixtures[pth]; ok {
+			fixture = override
+		}
+		content, err := fileutil.ReadStringFromFile(filepath.Join("testdata", "validate", fixture))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[pth] = content
+	}
+	return createProject(t, files)
+}
+
+func TestValidateNativeProjects(t *testing.T) {
+	const (
+		withUpdates    = `{"dependencies": {"expo": "~39.0.2", "expo-updates": "~0.3.2"}}`
+		withoutUpdates = `{"dependencies": {"expo": "~39.0.2"}}`
+		updateURL      = "https://exp.host/@bitrise/expo-app"
+	)
+	disabled := false
+
+	tests := []struct {
+		name        string
+		packageJSON string
+		fixtures    map[string]string
+		config      func(c *ExpoConfig)
+		platform    Platform
+		// wantIssues are substrings of the expected issues, in order.
+		wantIssues []string
+	}{
+		{
+			name:        "valid",
+			packageJSON: withUpdates,
+		},
+		{
+			name:        "missing update URL keys",
+			packageJSON: withUpdates,
+			fixtures: map[string]string{
+				"android/app/src/main/AndroidManifest.xml": "AndroidManifest.no-update-url.xml",
+				"ios/expoapp/Supporting/Expo.plist":        "Expo.no-update-url.plist",
+			},
+			wantIssues: []string{
+				"AndroidManifest.xml: expo.modules.updates.EXPO_UPDATE_URL meta-data is missing",
+				"Expo.plist: EXUpdatesURL key is missing",
+			},
+		},
+		{
+			name:        "missing update URL keys without expo-updates",
+			packageJSON: withoutUpdates,
+			fixtures: map[string]string{
+				"android/app/src/main/AndroidManifest.xml": "AndroidManifest.no-update-url.xml",
+				"ios/expoapp/Supporting/Expo.plist":        "Expo.no-update-url.plist",
+			},
+		},
+		{
+			name:        "missing update URL keys with updates disabled",
+			packageJSON: withUpdates,
+			fixtures: map[string]string{
+				"android/app/src/main/AndroidManifest.xml": "AndroidManifest.no-update-url.xml",
+				"ios/expoapp/Supporting/Expo.plist":        "Expo.no-update-url.plist",
+			},
+			config: func(c *ExpoConfig) { c.Updates.Enabled = &disabled },
+		},
+		{
+			name:        "different update URL",
+			packageJSON: withUpdates,
+			config:      func(c *ExpoConfig) { c.Updates.URL = "https://u.expo.dev/other" },
+			wantIssues: []string{
+				"EXPO_UPDATE_URL is " + updateURL + ", expected https://u.expo.dev/other",
+				"EXUpdatesURL is " + updateURL + ", expected https://u.expo.dev/other",
+			},
+		},
+		{
+			name:        "wrong applicationId",
+			packageJSON: withoutUpdates,
+			config:      func(c *ExpoConfig) { c.Android.Package = "com.bitrise.other" },
+			wantIssues:  []string{"build.gradle: applicationId is com.bitrise.expoapp, expected com.bitrise.other (expo.android.package)"},
+		},
+		{
+			name:        "bundle identifier resolved from project.pbxproj",
+			packageJSON: withoutUpdates,
+			config:      func(c *ExpoConfig) { c.IOS.BundleIdentifier = "com.bitrise.other" },
+			wantIssues:  []string{"project.pbxproj: bundle identifier is com.bitrise.expoapp, expected com.bitrise.other (expo.ios.bundleIdentifier)"},
+		},
+		{
+			name:        "literal bundle identifier in Info.plist",
+			packageJSON: withoutUpdates,
+			fixtures:    map[string]string{"ios/expoapp/Info.plist": "Info.literal-bundle-id.plist"},
+			config:      func(c *ExpoConfig) { c.IOS.BundleIdentifier = "com.bitrise.expoapp" },
+			wantIssues:  []string{"Info.plist: bundle identifier is com.bitrise.literal, expected com.bitrise.expoapp (expo.ios.bundleIdentifier)"},
+		},
+		{
+			name:        "only the selected platform is validated",
+			packageJSON: withoutUpdates,
+			platform:    PlatformIOS,
+			config:      func(c *ExpoConfig) { c.Android.Package = "com.bitrise.other" },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workdir := createValidateProject(t, tt.packageJSON, tt.fixtures)
+
+			config := ExpoConfig{}
+			config.Updates.URL = updateURL
+			config.Android.Package = "com.bitrise.expoapp"
+			config.IOS.BundleIdentifier = "com.bitrise.expoapp"
+			if tt.config != nil {
+				tt.config(&config)
+			}
+			platform := tt.platform
+			if platform == "" {
+				platform = PlatformAll
+			}
+
+			err := validateNativeProjects(workdir, platform, config)
+			if len(tt.wantIssues) == 0 {
+				if err != nil {
+					t.Fatalf("validateNativeProjects() error = %v", err)
+				}
+				return
+			}
+
+			validationErr, ok := err.(ValidationError)
+			if !ok {
+				t.Fatalf("validateNativeProjects() error = %v, want ValidationError", err)
+			}
+			if len(validationErr.Issues) != len(tt.wantIssues) {
+				t.Fatalf("issues = %q, want %q", validationErr.Issues, tt.wantIssues)
+			}
+			for i, want := range tt.wantIssues {
+				if !strings.Contains(validationErr.Issues[i], want) {
+					t.Errorf("issue = %q, want %q", validationErr.Issues[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestParsePlistStrings(t *testing.T) {
+	got, err := parsePlistStrings(filepath.Join("testdata", "validate", "Info.plist"))
+	if err != nil {
+		t.Fatalf("parsePlistStrings() error = %v", err)
+	}
+
+	// The nested CFBundleURLTypes and NSAppTransportSecurity values are skipped.
+	want := map[string]string{
+		"CFBundleDevelopmentRegion":  "$(DEVELOPMENT_LANGUAGE)",
+		"CFBundleDisplayName":        "expo-app",
+		"CFBundleExecutable":         "$(EXECUTABLE_NAME)",
+		"CFBundleIdentifier":         "$(PRODUCT_BUNDLE_IDENTIFIER)",
+		"CFBundleShortVersionString": "1.0.0",
+		"LSRequiresIPhoneOS":         "true",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parsePlistStrings() = %v, want %v", got, want)
+	}
+
+	if _, err := parsePlistStrings(filepath.Join("testdata", "validate", "missing.plist")); err == nil {
+		t.Error("parsePlistStrings() of a missing file succeeded")
+	}
+}